@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"go/format"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -35,7 +37,8 @@ func ParseIdlJsonFile(filename string) (*Idl, error) {
 	return ParseIdlJson(b)
 }
 
-// ParseIdlJson parses the given IDL JSON
+// ParseIdlJson parses the given IDL JSON.  If the IDL is semantically
+// invalid a ValidationErrors describing all the problems is returned.
 func ParseIdlJson(jsonData []byte) (*Idl, error) {
 
 	elems := []IdlJsonElem{}
@@ -44,6 +47,11 @@ func ParseIdlJson(jsonData []byte) (*Idl, error) {
 		return nil, err
 	}
 
+	err = ValidateIdl(elems)
+	if err != nil {
+		return nil, err
+	}
+
 	return NewIdl(elems), nil
 }
 
@@ -57,7 +65,8 @@ func MustParseIdlJson(jsonData []byte) *Idl {
 }
 
 // NewIdl creates a new Idl struct based on the slice of elements
-// parsed from the IDL JSON document
+// parsed from the IDL JSON document.  The elements are not validated;
+// call ValidateIdl first if they come from an untrusted source.
 func NewIdl(elems []IdlJsonElem) *Idl {
 	idl := &Idl{
 		elems:      elems,
@@ -79,7 +88,7 @@ func NewIdl(elems []IdlJsonElem) *Idl {
 			}
 			idl.interfaces[el.Name] = funcs
 		} else if el.Type == "struct" {
-			idl.structs[el.Name] = &Struct{Name: el.Name, Extends: el.Extends, Fields: el.Fields}
+			idl.structs[el.Name] = &Struct{Name: el.Name, Comment: el.Comment, Extends: el.Extends, Fields: el.Fields}
 		} else if el.Type == "enum" {
 			idl.enums[el.Name] = el.Values
 		}
@@ -129,11 +138,15 @@ type Function struct {
 // Represents an IDL struct
 type Struct struct {
 	Name    string
+	Comment string
 	Extends string
 	Fields  []Field
 
 	// fields in this struct, and its parents
 	allFields []Field
+
+	// names of allFields, used as a cache key
+	fieldKey string
 }
 
 // Represents a single Field on a struct or Function param
@@ -145,7 +158,7 @@ type Field struct {
 	Comment  string `json:"comment"`
 }
 
-func (f Field) goType(idl *Idl, optionalToPtr bool, pkgToStrip string) string {
+func (f Field) goType(idl *Idl, optionalToPtr bool, pkgToStrip string, naming NamingStrategy) string {
 	if f.IsArray {
 		f2 := Field{f.Name, f.Type, false, false, ""}
 
@@ -153,7 +166,7 @@ func (f Field) goType(idl *Idl, optionalToPtr bool, pkgToStrip string) string {
 		if f.Optional && optionalToPtr {
 			prefix = "*[]"
 		}
-		return prefix + f2.goType(idl, optionalToPtr, pkgToStrip)
+		return prefix + f2.goType(idl, optionalToPtr, pkgToStrip, naming)
 	}
 
 	_, isStruct := idl.structs[f.Type]
@@ -174,17 +187,17 @@ func (f Field) goType(idl *Idl, optionalToPtr bool, pkgToStrip string) string {
 		return prefix + "bool"
 	}
 
-	return prefix + capitalizeAndStripMatchingPkg(f.Type, pkgToStrip)
+	return prefix + goNameStripMatchingPkg(f.Type, pkgToStrip, naming)
 }
 
-func (f Field) zeroVal(idl *Idl, optionalToPtr bool, pkgToStrip string) interface{} {
+func (f Field) zeroVal(idl *Idl, optionalToPtr bool, pkgToStrip string, naming NamingStrategy) interface{} {
 
 	if f.Optional && optionalToPtr {
 		return "nil"
 	}
 
 	if f.IsArray {
-		return f.goType(idl, false, pkgToStrip) + "{}"
+		return f.goType(idl, false, pkgToStrip, naming) + "{}"
 	}
 
 	switch f.Type {
@@ -203,7 +216,7 @@ func (f Field) zeroVal(idl *Idl, optionalToPtr bool, pkgToStrip string) interfac
 		if f.Optional {
 			return "nil"
 		} else {
-			return capitalizeAndStripMatchingPkg(s.Name, pkgToStrip) + "{}"
+			return goNameStripMatchingPkg(s.Name, pkgToStrip, naming) + "{}"
 		}
 	}
 
@@ -301,6 +314,9 @@ type Idl struct {
 	// meta information about the contract
 	Meta Meta
 
+	// custom Go type mappings.  If nil, DefaultTypes is used
+	Types *TypeRegistry
+
 	// hashed elements
 	interfaces map[string][]Function
 	methods    map[string]Function
@@ -308,17 +324,45 @@ type Idl struct {
 	enums      map[string][]EnumValue
 }
 
+// typeRegistry returns idl.Types, or DefaultTypes if not set
+func (idl *Idl) typeRegistry() *TypeRegistry {
+	if idl.Types != nil {
+		return idl.Types
+	}
+	return DefaultTypes
+}
+
+// EncodeValue returns v with any values of a mapped Go type replaced by the
+// result of the TypeMapping Encode func.  If v contains no mapped types it
+// is returned unchanged.
+func (idl *Idl) EncodeValue(v interface{}) (interface{}, error) {
+	reg := idl.typeRegistry()
+	if v == nil || !reg.hasEncoders() {
+		return v, nil
+	}
+	return reg.encode(reflect.ValueOf(v))
+}
+
 func (idl *Idl) computeAllStructFields() {
 	for _, s := range idl.structs {
-		s.allFields = idl.computeStructFields(s, []Field{})
+		s.allFields = idl.computeStructFields(s, []Field{}, map[string]bool{})
+
+		names := make([]string, len(s.allFields))
+		for i, f := range s.allFields {
+			names[i] = f.Name
+		}
+		s.fieldKey = strings.Join(names, ",")
 	}
 }
 
-func (idl *Idl) computeStructFields(toAdd *Struct, allFields []Field) []Field {
-	if toAdd.Extends != "" {
+// computeStructFields returns the fields of toAdd, including inherited fields.
+// seen guards against cyclic extends chains, which ValidateIdl reports.
+func (idl *Idl) computeStructFields(toAdd *Struct, allFields []Field, seen map[string]bool) []Field {
+	seen[toAdd.Name] = true
+	if toAdd.Extends != "" && !seen[toAdd.Extends] {
 		parent, ok := idl.structs[toAdd.Extends]
 		if ok {
-			allFields = idl.computeStructFields(parent, allFields)
+			allFields = idl.computeStructFields(parent, allFields, seen)
 		}
 	}
 
@@ -346,20 +390,102 @@ func (idl *Idl) computeStructFields(toAdd *Struct, allFields []Field) []Field {
 // behavior of `encoding/json`, all nested struct fields marked optional will be generated as
 // pointers.  Otherwise there is no way to omit those fields from the struct during marshaling.
 //
+// Go identifiers are generated with LegacyNaming, so existing callers keep their
+// identifiers.  Use GenerateGoWithOptions, which defaults to MixedCapsNaming, to
+// select a different NamingStrategy.
+//
+// Panics if the generated code can't be formatted.  GenerateGoWithOptions returns the error instead.
+//
 func (idl *Idl) GenerateGo(defaultPkgName string, baseImport string, optionalToPtr bool) map[string][]byte {
-	pkgNameToGoCode := make(map[string][]byte)
-	for _, nsIdl := range partitionIdlByNamespace(idl, defaultPkgName) {
-		g := generateGo{idl,
-			nsIdl.idl,
-			nsIdl.pkgName,
-			optionalToPtr,
-			nsIdl.imports,
-			baseImport}
-		pkgNameToGoCode[nsIdl.pkgName] = g.generate()
+	pkgNameToGoCode, err := idl.GenerateGoWithOptions(GoOptions{
+		PkgName:       defaultPkgName,
+		BaseImport:    baseImport,
+		OptionalToPtr: optionalToPtr,
+		Naming:        LegacyNaming,
+	})
+	if err != nil {
+		panic(err)
 	}
 	return pkgNameToGoCode
 }
 
+// GoOptions holds the settings used by GenerateGoWithOptions.
+// See GenerateGo for a description of PkgName, BaseImport and OptionalToPtr.
+type GoOptions struct {
+	PkgName       string
+	BaseImport    string
+	OptionalToPtr bool
+
+	// Naming converts IDL names to Go identifiers.  JSON tags always use
+	// the IDL names.  If nil, MixedCapsNaming is used.  Set it to
+	// LegacyNaming to keep the identifiers of code generated by earlier
+	// versions.
+	Naming NamingStrategy
+
+	// If GenericOptionals is true, optional fields and function results are
+	// generated as barrister.Optional[T], and optional fields have
+	// "omitzero" in their JSON tag.  Unlike the other modes this can
+	// represent every value of an optional field, e.g. an int of 0.  It
+	// takes precedence over OptionalToPtr.
+	GenericOptionals bool
+
+	// Generated enum types have Values, IsValid and String methods, and
+	// MarshalJSON and UnmarshalJSON methods that return an error for values
+	// not in the IDL.  If AllowUnknownEnums is true the JSON methods aren't
+	// generated, so values added to the IDL later can still be decoded.
+	AllowUnknownEnums bool
+
+	// If Dispatchers is true, a barrister.Dispatcher is generated for each
+	// interface, and the generated NewServer registers handlers with
+	// AddDispatcher instead of AddHandler.
+	Dispatchers bool
+
+	// If Mocks is true, a mock implementation (<Iface>Mock) and a function
+	// returning an in-process Client (New<Iface>FakeClient) are generated
+	// for each interface.
+	Mocks bool
+}
+
+// GenerateGoWithOptions generates Go source code for the given Idl using opts.
+// A map is returned whose keys are the Go package names and values are the source
+// code for that package.
+//
+// The code is formatted with go/format and is identical each time it is generated
+// from the same IDL.  An error is returned if opts.Naming maps two IDL names to
+// the same Go identifier, or if the code can't be formatted, which usually means
+// a custom type mapping names an invalid Go type.
+func (idl *Idl) GenerateGoWithOptions(opts GoOptions) (map[string][]byte, error) {
+	if opts.Naming == nil {
+		opts.Naming = MixedCapsNaming
+	}
+	if err := idl.checkGoNames(opts.Naming); err != nil {
+		return nil, err
+	}
+
+	pkgNameToGoCode := make(map[string][]byte)
+	for _, nsIdl := range partitionIdlByNamespace(idl, opts.PkgName) {
+		g := generateGo{
+			idl:               idl,
+			pkgIdl:            nsIdl.idl,
+			pkgName:           nsIdl.pkgName,
+			optionalToPtr:     opts.OptionalToPtr,
+			genericOptionals:  opts.GenericOptionals,
+			imports:           nsIdl.imports,
+			baseImport:        opts.BaseImport,
+			naming:            opts.Naming,
+			allowUnknownEnums: opts.AllowUnknownEnums,
+			dispatch:          opts.Dispatchers,
+			mocks:             opts.Mocks,
+		}
+		code, err := format.Source(g.generate())
+		if err != nil {
+			return nil, fmt.Errorf("barrister: generated code for package %s is invalid: %s", nsIdl.pkgName, err)
+		}
+		pkgNameToGoCode[nsIdl.pkgName] = code
+	}
+	return pkgNameToGoCode, nil
+}
+
 // Method returns the Function related to the given method.
 // The method must be fully qualified with the interface name.
 // For example: "UserService.save"
@@ -393,9 +519,15 @@ func partitionIdlByNamespace(idl *Idl, defaultPkgName string) []namespacedIdl {
 		}
 	}
 
+	pkgs := make([]string, 0, len(pkgNameToIdlElems))
+	for pkg := range pkgNameToIdlElems {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
 	nsIdl := make([]namespacedIdl, 0)
-	for pkg, elems := range pkgNameToIdlElems {
-		elems = append(elems, metaElem)
+	for _, pkg := range pkgs {
+		elems := append(pkgNameToIdlElems[pkg], metaElem)
 		idl := NewIdl(elems)
 		imports := findAllImports(pkg, elems)
 		nsIdl = append(nsIdl, namespacedIdl{idl, pkg, imports})
@@ -926,15 +1058,35 @@ type Filter interface {
 	PostInvoke(r *RequestResponse) bool
 }
 
+// A Dispatcher calls the functions of an IDL interface on a handler without
+// reflection.  idl2go generates a Dispatcher for each interface when run
+// with -dispatch.  See Server.AddDispatcher.
+type Dispatcher interface {
+	// Dispatch converts params to the Go types of the IDL function's params,
+	// calls the function on handler, and returns its result.  params have
+	// been decoded by the Serializer, and their number has been checked.
+	//
+	// handler is the value registered with AddDispatcher, or its clone if it
+	// implements Cloneable.  idl is the Server's IDL.
+	Dispatch(idl *Idl, handler interface{}, function string, params []interface{}) (interface{}, error)
+}
+
 // NewJSONServer creates a Server for the given IDL that uses the JsonSerializer.
 // If forceASCII is true, then unicode characters will be escaped
 func NewJSONServer(idl *Idl, forceASCII bool) Server {
-	return NewServer(idl, &JsonSerializer{forceASCII})
+	return NewServer(idl, &JsonSerializer{ForceASCII: forceASCII})
 }
 
 // NewServer creates a Server for the given IDL and Serializer
 func NewServer(idl *Idl, ser Serializer) Server {
-	return Server{idl, ser, map[string]interface{}{}, make([]Filter, 0)}
+	return Server{
+		idl:         idl,
+		ser:         ser,
+		handlers:    map[string]interface{}{},
+		dispatchers: map[string]Dispatcher{},
+		filters:     make([]Filter, 0),
+		goMethods:   map[string]string{},
+	}
 }
 
 // Server represents a handler for Barrister IDL file.
@@ -945,6 +1097,21 @@ type Server struct {
 	ser      Serializer
 	handlers map[string]interface{}
 	filters  []Filter
+
+	// Dispatcher for each interface registered with AddDispatcher
+	dispatchers map[string]Dispatcher
+
+	// Go method name for each IDL method, resolved by AddHandler
+	goMethods map[string]string
+
+	// returned by the "rpc.discover" method, if EnableDiscover was called
+	discover *OpenRPCDocument
+}
+
+// EnableDiscover makes the Server answer the OpenRPC "rpc.discover" method
+// with the OpenRPC document for its IDL.  See Idl.OpenRPC for how info is used.
+func (s *Server) EnableDiscover(info OpenRPCInfo) {
+	s.discover = s.idl.OpenRPC(info)
 }
 
 // AddFilter registers a Filter implementation with the Server.
@@ -971,14 +1138,15 @@ func (s *Server) AddHandler(iface string, impl interface{}) {
 	var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
 	elem := reflect.ValueOf(impl)
+	goMethods := map[string]string{}
 	for _, idlFunc := range ifaceFuncs {
-		fname := capitalize(idlFunc.Name)
-		fn := elem.MethodByName(fname)
+		fname, fn := findMethod(elem, idlFunc.Name)
 		if fn == zeroVal {
 			msg := fmt.Sprintf("barrister: %s impl has no method named: %s",
 				iface, fname)
 			panic(msg)
 		}
+		goMethods[iface+"."+idlFunc.Name] = fname
 
 		fnType := fn.Type()
 		if fnType.NumIn() != len(idlFunc.Params) {
@@ -1007,6 +1175,45 @@ func (s *Server) AddHandler(iface string, impl interface{}) {
 	}
 
 	s.handlers[iface] = impl
+	delete(s.dispatchers, iface)
+	for k, v := range goMethods {
+		s.goMethods[k] = v
+	}
+}
+
+// AddDispatcher associates the given impl with the IDL interface, like
+// AddHandler.  Calls to impl are made by d, so no reflection is used to
+// convert params or invoke methods.  Filters and Cloneable work the same
+// as they do for handlers registered with AddHandler.
+//
+// Typically d is an idl2go generated Dispatcher, and impl is checked against
+// the IDL by the Go compiler instead of at runtime.  AddDispatcher panics if
+// the IDL has no interface named iface, or if d is nil.
+func (s *Server) AddDispatcher(iface string, impl interface{}, d Dispatcher) {
+	if _, ok := s.idl.interfaces[iface]; !ok {
+		msg := fmt.Sprintf("barrister: IDL has no interface: %s", iface)
+		panic(msg)
+	}
+	if d == nil {
+		msg := fmt.Sprintf("barrister: nil Dispatcher for interface: %s", iface)
+		panic(msg)
+	}
+	s.handlers[iface] = impl
+	s.dispatchers[iface] = d
+}
+
+// findMethod returns the method on elem for the given IDL function name.
+// Each of the handlerNamings strategies is tried in order.  If no method is
+// found, the name derived from the first strategy and zeroVal are returned.
+func findMethod(elem reflect.Value, idlName string) (string, reflect.Value) {
+	for _, naming := range handlerNamings {
+		fname := naming(idlName)
+		fn := elem.MethodByName(fname)
+		if fn != zeroVal {
+			return fname, fn
+		}
+	}
+	return handlerNamings[0](idlName), zeroVal
 }
 
 // validate ensurse that the given implType matches the expected IDL type.
@@ -1017,6 +1224,7 @@ func (s *Server) AddHandler(iface string, impl interface{}) {
 func (s *Server) validate(idlField Field, implType reflect.Type, path string) {
 	testVal := idlField.testVal(s.idl)
 	conv := newConvert(s.idl, &idlField, implType, testVal, "")
+	conv.checkOnly = true
 	_, err := conv.run()
 	if err != nil {
 		msg := fmt.Sprintf("barrister: %s has invalid type: %s reason: %s", path, implType, err)
@@ -1029,7 +1237,9 @@ func (s *Server) validate(idlField Field, implType reflect.Type, path string) {
 // request is a single or batch call.
 //
 // InvokeBytess delegates to InvokeOne and then marshals the result using the
-// Serializer and returns the serialized byte slice.
+// Serializer and returns the serialized byte slice.  A result that can't be
+// marshaled, such as a generated enum set to a value that isn't in the IDL,
+// is returned as a -32603 error.
 func (s *Server) InvokeBytes(headers Headers, req []byte) []byte {
 
 	// determine if batch or single
@@ -1051,7 +1261,16 @@ func (s *Server) InvokeBytes(headers Headers, req []byte) []byte {
 
 		b, err := s.ser.Marshal(batchResp)
 		if err != nil {
-			panic(err)
+			// replace the responses that can't be marshaled with errors
+			for i := range batchResp {
+				if _, err := s.ser.Marshal(&batchResp[i]); err != nil {
+					batchResp[i] = *marshalErr(batchReq[i].Method, &batchResp[i], err)
+				}
+			}
+			b, err = s.ser.Marshal(batchResp)
+			if err != nil {
+				panic(err)
+			}
 		}
 		return b
 	}
@@ -1067,19 +1286,34 @@ func (s *Server) InvokeBytes(headers Headers, req []byte) []byte {
 
 	b, err := s.ser.Marshal(resp)
 	if err != nil {
-		panic(err)
+		b, err = s.ser.Marshal(marshalErr(rpcReq.Method, resp, err))
+		if err != nil {
+			panic(err)
+		}
 	}
 	return b
 }
 
+// marshalErr returns the error response sent in place of resp when resp
+// can't be marshaled, e.g. because its result contains an invalid enum value
+func marshalErr(method string, resp *JsonRpcResponse, err error) *JsonRpcResponse {
+	msg := fmt.Sprintf("barrister: method '%s' unable to marshal result: %s", method, err)
+	return &JsonRpcResponse{Jsonrpc: "2.0", Id: resp.Id, Error: &JsonRpcError{Code: -32603, Message: msg}}
+}
+
 // InvokeOne handles a single JSON-RPC request, delegating to Call.  If the special "barrister-idl"
-// method is handled, InvokeOne will return the IDL associated with this Server.
+// method is handled, InvokeOne will return the IDL associated with this Server.  If EnableDiscover
+// has been called, the "rpc.discover" method returns the OpenRPC document for the IDL.
 func (s *Server) InvokeOne(headers Headers, rpcReq *JsonRpcRequest) *JsonRpcResponse {
 	if rpcReq.Method == "barrister-idl" {
 		// handle 'barrister-idl' method
 		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Result: s.idl.elems}
 	}
 
+	if rpcReq.Method == "rpc.discover" && s.discover != nil {
+		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Result: s.discover}
+	}
+
 	// handle normal RPC method executions
 	var result interface{}
 	var err error
@@ -1090,6 +1324,15 @@ func (s *Server) InvokeOne(headers Headers, rpcReq *JsonRpcRequest) *JsonRpcResp
 		result, err = s.Call(headers, rpcReq.Method)
 	}
 
+	if err == nil {
+		// encode any custom mapped types in the result
+		result, err = s.idl.EncodeValue(result)
+		if err != nil {
+			msg := fmt.Sprintf("barrister: method '%s' unable to encode result: %s", rpcReq.Method, err)
+			err = &JsonRpcError{Code: -32603, Message: msg}
+		}
+	}
+
 	if err == nil {
 		// successful Call
 		return &JsonRpcResponse{Jsonrpc: "2.0", Id: rpcReq.Id, Result: result}
@@ -1134,7 +1377,8 @@ func (s *Server) CallBatch(headers Headers, batch []JsonRpcRequest) []JsonRpcRes
 //
 // 5) Request parameters are validated against the IDL.  If the request violates the IDL an error is returned.
 //
-// 6) The handler function is invoked
+// 6) The handler function is invoked.  If the handler was registered with AddDispatcher, steps 5 and 6 are
+// performed by its Dispatcher.
 //
 // 7) If the Server has one or more Filters registered, PostInvoke() will be called on each Filter.  Filters are
 // called in the reverse order.  If any Filter returns false, filter execution will stop.
@@ -1148,7 +1392,11 @@ func (s *Server) Call(headers Headers, method string, params ...interface{}) (in
 		return nil, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", method)}
 	}
 
-	iface, fname := parseMethod(method)
+	iface, fname := parseMethod(method, LegacyNaming)
+	goName, ok := s.goMethods[method]
+	if ok {
+		fname = goName
+	}
 
 	handler, ok := s.handlers[iface]
 	if !ok {
@@ -1162,20 +1410,24 @@ func (s *Server) Call(headers Headers, method string, params ...interface{}) (in
 		handler = c.CloneForReq(headers)
 	}
 
-	elem := reflect.ValueOf(handler)
-	fn := elem.MethodByName(fname)
-	if fn == zeroVal {
-		return nil, &JsonRpcError{Code: -32601,
-			Message: fmt.Sprintf("Function %s not found on handler %s", fname, iface)}
-	}
+	dispatcher, dispatch := s.dispatchers[iface]
 
-	//fmt.Printf("Call method: %s  params: %v\n", method, params)
+	var fn reflect.Value
+	if !dispatch {
+		elem := reflect.ValueOf(handler)
+		fn = elem.MethodByName(fname)
+		if fn == zeroVal {
+			return nil, &JsonRpcError{Code: -32601,
+				Message: fmt.Sprintf("Function %s not found on handler %s", fname, iface)}
+		}
 
-	// check params
-	fnType := fn.Type()
-	if fnType.NumIn() != len(params) {
-		return nil, &JsonRpcError{Code: -32602,
-			Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, fnType.NumIn(), len(params))}
+		//fmt.Printf("Call method: %s  params: %v\n", method, params)
+
+		// check params
+		if fn.Type().NumIn() != len(params) {
+			return nil, &JsonRpcError{Code: -32602,
+				Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, fn.Type().NumIn(), len(params))}
+		}
 	}
 
 	if len(idlFunc.Params) != len(params) {
@@ -1194,35 +1446,43 @@ func (s *Server) Call(headers Headers, method string, params ...interface{}) (in
 		}
 	}
 
-	// convert params
-	paramVals := []reflect.Value{}
-	for x, param := range params {
-		desiredType := fnType.In(x)
-		idlField := idlFunc.Params[x]
-		path := fmt.Sprintf("param[%d]", x)
-		paramConv := newConvert(s.idl, &idlField, desiredType, param, path)
-		converted, err := paramConv.run()
-		if err != nil {
-			return nil, &JsonRpcError{Code: -32602, Message: err.Error()}
+	if dispatch {
+		result, err := dispatcher.Dispatch(s.idl, handler, idlFunc.Name, params)
+		if ip, ok := err.(*invalidParams); ok {
+			return nil, &ip.JsonRpcError
+		}
+		rr.Result, rr.Err = result, err
+	} else {
+		// convert params
+		paramVals := []reflect.Value{}
+		for x, param := range params {
+			desiredType := fn.Type().In(x)
+			idlField := idlFunc.Params[x]
+			path := fmt.Sprintf("param[%d]", x)
+			paramConv := newConvert(s.idl, &idlField, desiredType, param, path)
+			converted, err := paramConv.run()
+			if err != nil {
+				return nil, &JsonRpcError{Code: -32602, Message: err.Error()}
+			}
+			paramVals = append(paramVals, converted)
 		}
-		paramVals = append(paramVals, converted)
-	}
 
-	// make the call
-	ret := fn.Call(paramVals)
-	if len(ret) != 2 {
-		msg := fmt.Sprintf("Method %s did not return 2 values. len(ret)=%d", method, len(ret))
-		return nil, &JsonRpcError{Code: -32603, Message: msg}
-	}
+		// make the call
+		ret := fn.Call(paramVals)
+		if len(ret) != 2 {
+			msg := fmt.Sprintf("Method %s did not return 2 values. len(ret)=%d", method, len(ret))
+			return nil, &JsonRpcError{Code: -32603, Message: msg}
+		}
 
-	ret0 := ret[0].Interface()
-	ret1 := ret[1].Interface()
+		ret0 := ret[0].Interface()
+		ret1 := ret[1].Interface()
 
-	rr.Result = ret0
-	if ret1 != nil {
-		e, ok := ret1.(error)
-		if ok {
-			rr.Err = e
+		rr.Result = ret0
+		if ret1 != nil {
+			e, ok := ret1.(error)
+			if ok {
+				rr.Err = e
+			}
 		}
 	}
 
@@ -1266,21 +1526,18 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 }
 
 // parseMethod takes a JSON-RPC method string and splits it on period, returning
-// the part to the left of the period, and capitalizing the part to the right.
+// the part to the left of the period, and the part to the right converted to
+// a Go method name with the given NamingStrategy.
 //
-// For example, "UserService.save" would return: "UserService", "Save"
+// For example, with LegacyNaming "UserService.save_all" would return: "UserService", "Save_all"
+// and with MixedCapsNaming it would return: "UserService", "SaveAll"
 //
 // If the method does not contain a period, the whole method and an empty string
 // are returned.  For example, "doFoo" would return: "doFoo", ""
-func parseMethod(method string) (string, string) {
+func parseMethod(method string, naming NamingStrategy) (string, string) {
 	i := strings.Index(method, ".")
 	if i > -1 && i < (len(method)-1) {
-		iface := method[0:i]
-		if i < (len(method) - 2) {
-			return iface, strings.ToUpper(method[i+1:i+2]) + method[i+2:]
-		} else {
-			return iface, strings.ToUpper(method[i+1:])
-		}
+		return method[0:i], naming(method[i+1:])
 	}
 	return method, ""
 }
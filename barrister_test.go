@@ -1,12 +1,16 @@
 package barrister
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"go/format"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -85,6 +89,345 @@ func TestIdl2Go(t *testing.T) {
 	}
 }
 
+func TestGenerateGoNaming(t *testing.T) {
+	idl := parseTestIdl()
+
+	legacy := string(idl.GenerateGo("conform", "", false)["conform"])
+	code, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform", Naming: LegacyNaming})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, string(code["conform"]), legacy)
+
+	code, err = idl.GenerateGoWithOptions(GoOptions{PkgName: "conform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixed := string(code["conform"])
+
+	expected := map[string][]string{
+		legacy: []string{
+			"To_repeat       string `json:\"to_repeat\"`",
+			"Say_hi() (HiResponse, error)",
+		},
+		mixed: []string{
+			"ToRepeat       string `json:\"to_repeat\"`",
+			"PersonID  string `json:\"personId\"`",
+			"SayHi() (HiResponse, error)",
+			"func (_p AProxy) RepeatNum(num int64, count int64) ([]int64, error) {",
+			"_p.client.Call(\"A.repeat_num\", num, count)",
+		},
+	}
+	for code, lines := range expected {
+		for _, s := range lines {
+			if !strings.Contains(code, s) {
+				t.Errorf("Generated code does not contain: %s", s)
+			}
+		}
+	}
+}
+
+func TestGenerateGoNameCollisions(t *testing.T) {
+	cases := []struct {
+		idl *Idl
+		err string
+	}{
+		{NewBuilder().Struct("Item").Field("api_url", "string").Field("apiURL", "string").MustBuild(),
+			`barrister: struct Item: IDL names "api_url" and "apiURL" are both generated as Go name APIURL`},
+		{NewBuilder().Struct("Base").Field("user_id", "string").
+			Struct("Item").Extends("Base").Field("userID", "string").MustBuild(),
+			`barrister: struct Item: IDL names "user_id" and "userID" are both generated as Go name UserID`},
+		{NewBuilder().Interface("Svc").Function("get_s", "bool").Function("getS", "bool").MustBuild(),
+			`barrister: interface Svc: IDL names "get_s" and "getS" are both generated as Go name GetS`},
+		{NewBuilder().Enum("Color").Value("dark_red").Value("darkRed").MustBuild(),
+			`barrister: enum Color: IDL names "dark_red" and "darkRed" are both generated as Go name DarkRed`},
+		{NewBuilder().Struct("user_info").Field("a", "string").Struct("UserInfo").Field("b", "string").MustBuild(),
+			`barrister: IDL: IDL names "user_info" and "UserInfo" are both generated as Go name UserInfo`},
+	}
+	for _, c := range cases {
+		_, err := c.idl.GenerateGoWithOptions(GoOptions{PkgName: "svc", Naming: MixedCapsNaming})
+		if err == nil {
+			t.Errorf("expected error: %s", c.err)
+			continue
+		}
+		Equals(t, err.Error(), c.err)
+
+		_, err = c.idl.GenerateSkeleton(GoOptions{PkgName: "svc", Naming: MixedCapsNaming})
+		NotEquals(t, err, nil)
+	}
+
+	// legacy names of the same IDL don't collide
+	_, err := cases[0].idl.GenerateGoWithOptions(GoOptions{PkgName: "svc", Naming: LegacyNaming})
+	Equals(t, err, nil)
+}
+
+func TestGenerateGoComments(t *testing.T) {
+	idl := parseTestIdl()
+	pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(pkgNameToCode["conform"])
+
+	expected := []string{
+		"\t// mult comment\n\tMathOpMultiply",
+		"// testing struct inheritance\ntype RepeatResponse struct {",
+		"// a second interface to prove that the server dispatcher\n// understands how to distinguish between interfaces in a contract\ntype B interface {",
+		"\t// simply returns p.personId\n\t//\n\t// we use this to test the '[optional]' enforcement,\n\t// as we invoke it with a null email\n\tPutPerson(",
+		"// returns the square root of a\nfunc (_p AProxy) Sqrt(a float64) (float64, error) {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s", s)
+		}
+	}
+}
+
+func TestGenerateGoEnums(t *testing.T) {
+	idl := parseTestIdl()
+
+	strict, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(strict["conform"])
+	expected := []string{
+		"StatusErr Status = \"err\"",
+		"func (Status) Values() []Status {\n\treturn []Status{StatusOk, StatusErr}\n}",
+		"func (e Status) IsValid() bool {\n\tswitch e {\n\tcase StatusOk, StatusErr:",
+		"return fmt.Errorf(\"invalid Status value: %q\", s)",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s", s)
+		}
+	}
+
+	tolerant, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform", AllowUnknownEnums: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code = string(tolerant["conform"])
+	True(t, strings.Contains(code, "func (e Status) IsValid() bool {"))
+	False(t, strings.Contains(code, "MarshalJSON"))
+	False(t, strings.Contains(code, "\"encoding/json\""))
+}
+
+func TestGenerateGoValidate(t *testing.T) {
+	idl := NewBuilder().
+		Enum("Color").Value("red").Value("blue").
+		Struct("Base").Field("tags", "[]string").
+		Struct("Inner").Field("c", "Color").Field("oc", "Color [optional]").
+		Struct("Outer").Extends("Base").Field("in", "Inner").Field("oin", "Inner [optional]").
+		Field("ins", "[]Inner").Field("oins", "[]Inner [optional]").Field("cs", "[]Color").
+		MustBuild()
+
+	expected := map[bool][]string{
+		false: []string{
+			"func (s Outer) Validate() error {\n\tif err := s.Base.Validate(); err != nil {\n\t\treturn err\n\t}",
+			"if s.Oin != nil {\n\t\tif err := s.Oin.Validate(); err != nil {",
+			"if s.Ins == nil {\n\t\treturn fmt.Errorf(\"ins: required value is missing\")",
+			"for i, v := range s.Oins {\n\t\tif err := v.Validate(); err != nil {\n\t\t\treturn fmt.Errorf(\"oins[%d].%s\", i, err)",
+			"if !v.IsValid() {\n\t\t\treturn fmt.Errorf(\"cs[%d]: invalid value: %q\", i, v)",
+			"if s.Oc != \"\" && !s.Oc.IsValid() {",
+		},
+		true: []string{
+			"if s.Oins != nil {\n\t\tfor i, v := range *s.Oins {",
+			"if s.Oc != nil && !s.Oc.IsValid() {\n\t\treturn fmt.Errorf(\"oc: invalid value: %q\", *s.Oc)",
+		},
+	}
+	for optionalToPtr, lines := range expected {
+		pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "vt", OptionalToPtr: optionalToPtr})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := string(pkgNameToCode["vt"])
+		for _, s := range lines {
+			if !strings.Contains(code, s) {
+				t.Errorf("Generated code does not contain: %s\n%s", s, code)
+			}
+		}
+	}
+}
+
+func TestGenerateGoProxies(t *testing.T) {
+	idl := parseTestIdl()
+	code := string(idl.GenerateGo("conform", "", true)["conform"])
+
+	expected := []string{
+		"_idlOnce.Do(func() {\n\t\t_idl = barrister.MustParseIdlJson([]byte(IdlJsonRaw))",
+		"var _ret RepeatResponse\n\tif _err := _ret.UnmarshalIdl(_res); _err != nil {\n\t\treturn RepeatResponse{}, _err",
+		"if _x, _ok := _res.([]int64); _ok {\n\t\t_ret = _x\n\t} else {",
+		"if _res == nil {\n\t\treturn nil, nil\n\t}\n\tvar _ret *string\n\tif _x, _err := barrister.DecodeString(_res); _err != nil {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s\n%s", s, code)
+		}
+	}
+
+	// proxies that don't use mapped types don't need reflection
+	for _, s := range []string{"\"reflect\"", "barrister.Convert"} {
+		if strings.Contains(code, s) {
+			t.Errorf("Generated code contains: %s\n%s", s, code)
+		}
+	}
+}
+
+func TestGenerateGoBatch(t *testing.T) {
+	idl := parseTestIdl()
+	code := string(idl.GenerateGo("conform", "", true)["conform"])
+
+	expected := []string{
+		"func NewABatch(batch *barrister.Batch) ABatch { return ABatch{batch} }",
+		"func (_b ABatch) Add(a int64, b int64) *barrister.Future[int64] {\n\t_params := []interface{}{a, b}\n" +
+			"\treturn barrister.AddCall(_b.Batch, \"A.add\", _params, func(_res interface{}) (int64, error) {\n" +
+			"\t\tvar _ret int64\n\t\tif _x, _err := barrister.DecodeInt(_res); _err != nil {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s\n%s", s, code)
+		}
+	}
+}
+
+func TestGenerateGoDispatchers(t *testing.T) {
+	idl := parseTestIdl()
+	pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform", Dispatchers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(pkgNameToCode["conform"])
+
+	expected := []string{
+		"h, ok := handler.(A)\n\tif !ok {\n\t\treturn nil, barrister.HandlerError(\"A\", handler)",
+		"case \"add\":\n\t\tvar _p0 int64\n\t\tif _x, _err := barrister.DecodeInt(params[0]); _err != nil {\n\t\t\treturn nil, barrister.ParamError(_err, 0)",
+		"return h.Calc(_p0, _p1)",
+		"Message: \"Unsupported method: A.\" + function}",
+		"_svr.AddDispatcher(\"B\", b, BDispatcher{})",
+		"s, err := barrister.DecodeEnum(v, _MathOpValues)",
+		"if err := s.Response.UnmarshalIdl(m); err != nil {",
+		"if _v, ok, err := barrister.FieldValue(m, \"items\", false); err != nil {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s\n%s", s, code)
+		}
+	}
+	if strings.Contains(code, "AddHandler") {
+		t.Errorf("Generated code calls AddHandler\n%s", code)
+	}
+}
+
+func TestGenerateGoMocks(t *testing.T) {
+	idl := parseTestIdl()
+	for _, dispatch := range []bool{false, true} {
+		pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform", Mocks: true, Dispatchers: dispatch})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := string(pkgNameToCode["conform"])
+
+		expected := []string{
+			"type BMock struct {\n\tbarrister.MockRecorder\n\n\tEchoFunc func(s string) (string, error)\n}",
+			"func (_m *BMock) Echo(s string) (string, error) {\n\t_m.MockRecorder.Record(\"B.echo\", s)",
+			"return \"\", &barrister.JsonRpcError{Code: -32601, Message: \"B.echo: EchoFunc not set\"}",
+			"return _m.EchoFunc(s)",
+		}
+		if dispatch {
+			expected = append(expected, "_svr.AddDispatcher(\"B\", b, BDispatcher{})\n\treturn barrister.NewServerClient")
+		} else {
+			expected = append(expected, "_svr.AddHandler(\"B\", b)\n\treturn barrister.NewServerClient")
+		}
+		for _, s := range expected {
+			if !strings.Contains(code, s) {
+				t.Errorf("Generated code does not contain: %s\n%s", s, code)
+			}
+		}
+	}
+}
+
+func TestGenerateGoGenericOptionals(t *testing.T) {
+	idl := NewBuilder().
+		Enum("Color").Value("red").
+		Struct("Item").
+		Field("count", "int [optional]").
+		Field("color", "Color [optional]").
+		Field("tags", "[]string [optional]").
+		Interface("Svc").Function("get", "Item [optional]", "id string").
+		MustBuild()
+
+	for _, dispatch := range []bool{false, true} {
+		pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "svc", GenericOptionals: true, OptionalToPtr: true, Dispatchers: dispatch, Mocks: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := string(pkgNameToCode["svc"])
+
+		expected := []string{
+			"Count barrister.Optional[int64]    `json:\"count,omitzero\"`",
+			"Color barrister.Optional[Color]    `json:\"color,omitzero\"`",
+			"\tif s.Color.Set {\n\t\tif !s.Color.Value.IsValid() {",
+			"\t\tvar _o int64\n",
+			"\t\ts.Count = barrister.Some(_o)\n\t} else if _, null := m[\"count\"]; null {\n\t\ts.Count = barrister.Optional[int64]{Null: true}\n\t}",
+			"Get(id string) (barrister.Optional[Item], error)",
+			"\tif _res == nil {\n\t\treturn barrister.Optional[Item]{Null: true}, nil\n\t}",
+		}
+		for _, s := range expected {
+			if !strings.Contains(code, s) {
+				t.Errorf("Generated code does not contain: %s\n%s", s, code)
+			}
+		}
+	}
+}
+
+func TestGenerateGoDeterministic(t *testing.T) {
+	idl := NewBuilder().
+		Namespace("zz").Enum("A").Value("a").Enum("B").Value("b").Enum("C").Value("c").
+		Namespace("aa").Enum("D").Value("d").Struct("E").Field("z", "zz.C").
+		Namespace("").Interface("Svc").Function("f", "aa.E", "a zz.A").
+		MustBuild()
+
+	first, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(first), 3)
+	for i := 0; i < 20; i++ {
+		again, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "svc"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		DeepEquals(t, again, first)
+	}
+
+	header := regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+	for pkg, code := range first {
+		True(t, header.Match(code))
+		formatted, err := format.Source(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(formatted, code) {
+			t.Errorf("Generated code for %s is not gofmt formatted", pkg)
+		}
+	}
+
+	zz := string(first["zz"])
+	True(t, strings.Index(zz, "type A string") < strings.Index(zz, "type B string"))
+	True(t, strings.Index(zz, "type B string") < strings.Index(zz, "type C string"))
+}
+
+func TestGenerateGoFormatError(t *testing.T) {
+	idl := parseTestIdl()
+	idl.Types = NewTypeRegistry()
+	idl.Types.MapIdl("Person.email", TypeMapping{GoName: "not a type"})
+
+	_, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "conform"})
+	NotEquals(t, err, nil)
+	True(t, strings.HasPrefix(err.Error(), "barrister: generated code for package conform is invalid: "))
+}
+
 func TestParseMethod(t *testing.T) {
 	cases := [][]string{
 		[]string{"B.echo", "B", "Echo"},
@@ -94,10 +437,33 @@ func TestParseMethod(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		iface, fname := parseMethod(c[0])
+		iface, fname := parseMethod(c[0], LegacyNaming)
 		Equals(t, iface, c[1])
 		Equals(t, fname, c[2])
 	}
+
+	iface, fname := parseMethod("A.say_hi", MixedCapsNaming)
+	Equals(t, iface, "A")
+	Equals(t, fname, "SayHi")
+}
+
+func TestNamingStrategies(t *testing.T) {
+	cases := [][]string{
+		[]string{"to_repeat", "To_repeat", "ToRepeat"},
+		[]string{"say_hi", "Say_hi", "SayHi"},
+		[]string{"personId", "PersonId", "PersonID"},
+		[]string{"putPerson", "PutPerson", "PutPerson"},
+		[]string{"userURLPath", "UserURLPath", "UserURLPath"},
+		[]string{"HTTPServer", "HTTPServer", "HTTPServer"},
+		[]string{"api_url", "Api_url", "APIURL"},
+		[]string{"a", "A", "A"},
+		[]string{"RepeatResponse", "RepeatResponse", "RepeatResponse"},
+	}
+
+	for _, c := range cases {
+		Equals(t, LegacyNaming(c[0]), c[1])
+		Equals(t, MixedCapsNaming(c[0]), c[2])
+	}
 }
 
 func TestParseStuff(t *testing.T) {
@@ -301,3 +667,47 @@ func BenchmarkConvertStruct(b *testing.B) {
 		}
 	}
 }
+
+type IdiomaticRepeatRequest struct {
+	ToRepeat       string `json:"to_repeat"`
+	Count          int64
+	ForceUppercase bool
+}
+
+type TaggedPersonBase struct {
+	Id string `json:"personId"`
+}
+
+type TaggedPerson struct {
+	TaggedPersonBase
+	First string  `json:"firstName"`
+	Last  string  `json:"lastName"`
+	Email *string `json:"email"`
+}
+
+type AmbiguousRepeatRequest struct {
+	To_Repeat      string
+	TO_REPEAT      string
+	Count          int64
+	ForceUppercase bool
+}
+
+func TestConvertStructFieldResolution(t *testing.T) {
+	idl := parseTestIdl()
+
+	input := map[string]interface{}{"to_repeat": "hi", "count": 2.0, "force_uppercase": true}
+	val, err := Convert(idl, &Field{Type: "RepeatRequest"}, reflect.TypeOf(IdiomaticRepeatRequest{}), input, "")
+	Equals(t, err, nil)
+	DeepEquals(t, val, IdiomaticRepeatRequest{"hi", 2, true})
+
+	input = map[string]interface{}{"personId": "1", "firstName": "Bob", "lastName": "Smith"}
+	val, err = Convert(idl, &Field{Type: "Person"}, reflect.TypeOf(TaggedPerson{}), input, "")
+	Equals(t, err, nil)
+	DeepEquals(t, val, TaggedPerson{TaggedPersonBase{"1"}, "Bob", "Smith", nil})
+
+	input = map[string]interface{}{"to_repeat": "hi", "count": 2.0, "force_uppercase": true}
+	_, err = Convert(idl, &Field{Type: "RepeatRequest"}, reflect.TypeOf(AmbiguousRepeatRequest{}), input, "")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous fields for IDL field: RepeatRequest.to_repeat (To_Repeat, TO_REPEAT)") {
+		t.Errorf("Expected ambiguous field error, got: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package barrister
+
+import (
+	"errors"
+	"strconv"
+)
+
+var errBatchNotSent = errors.New("barrister: batch has not been sent")
+
+// Batch queues calls to send to a Client in a single JSON-RPC batch request.
+// The batch types generated by idl2go queue typed calls on a Batch.  Calls
+// to several interfaces may share a Batch.
+//
+// A Batch is not safe for concurrent use, and can only be sent once.
+type Batch struct {
+	client Client
+	calls  []batchCall
+	sent   bool
+}
+
+type batchCall struct {
+	req  JsonRpcRequest
+	done func(result interface{}, err error)
+}
+
+// NewBatch creates a Batch that is sent using c
+func NewBatch(c Client) *Batch {
+	return &Batch{client: c}
+}
+
+// Len returns the number of queued calls
+func (b *Batch) Len() int {
+	return len(b.calls)
+}
+
+// Add queues a call to method.  When the batch is sent, done is called with
+// the result of the call, or the error it returned.
+func (b *Batch) Add(method string, params []interface{}, done func(result interface{}, err error)) {
+	id := strconv.Itoa(len(b.calls) + 1)
+	req := JsonRpcRequest{Jsonrpc: "2.0", Id: id, Method: method, Params: params}
+	b.calls = append(b.calls, batchCall{req, done})
+}
+
+// Send sends the queued calls in a single request and completes each of
+// them.  An error is returned if the request as a whole failed, e.g. due to
+// a transport error, in which case each call fails with that error too.
+// Errors returned by individual calls are only reported to those calls.
+func (b *Batch) Send() error {
+	if b.sent {
+		return errors.New("barrister: batch has already been sent")
+	}
+	b.sent = true
+	if len(b.calls) == 0 {
+		return nil
+	}
+
+	reqs := make([]JsonRpcRequest, len(b.calls))
+	for x, call := range b.calls {
+		reqs[x] = call.req
+	}
+	resps := b.client.CallBatch(reqs)
+
+	// RemoteClient returns a single response without an id if the
+	// batch couldn't be sent
+	if len(resps) == 1 && resps[0].Id == "" && resps[0].Error != nil {
+		for _, call := range b.calls {
+			call.done(nil, resps[0].Error)
+		}
+		return resps[0].Error
+	}
+
+	byId := make(map[string]JsonRpcResponse, len(resps))
+	for _, resp := range resps {
+		byId[resp.Id] = resp
+	}
+	for _, call := range b.calls {
+		resp, ok := byId[call.req.Id]
+		if !ok {
+			msg := "barrister: " + call.req.Method + ": no response in batch for request id " + call.req.Id
+			call.done(nil, &JsonRpcError{Code: -32603, Message: msg})
+		} else if resp.Error != nil {
+			call.done(nil, resp.Error)
+		} else {
+			call.done(resp.Result, nil)
+		}
+	}
+	return nil
+}
+
+// Future is the result of a call queued on a Batch.  It is completed when
+// the Batch is sent.
+type Future[T any] struct {
+	value T
+	err   error
+	done  bool
+}
+
+// Get returns the result of the call.  An error is returned if the call
+// failed or the Batch hasn't been sent.
+func (f *Future[T]) Get() (T, error) {
+	if !f.done {
+		var zero T
+		return zero, errBatchNotSent
+	}
+	return f.value, f.err
+}
+
+// Done returns true once the call has completed
+func (f *Future[T]) Done() bool {
+	return f.done
+}
+
+func (f *Future[T]) complete(value T, err error) {
+	f.value, f.err, f.done = value, err, true
+}
+
+// AddCall queues a call to method on b.  When b is sent, the result is
+// converted to T by decode, and the returned Future is completed.  Errors
+// returned by decode are prefixed with the method, e.g.
+// "barrister: A.get: result.name: ...", unless they are a *JsonRpcError.
+func AddCall[T any](b *Batch, method string, params []interface{}, decode func(result interface{}) (T, error)) *Future[T] {
+	f := &Future[T]{}
+	b.Add(method, params, func(result interface{}, err error) {
+		if err != nil {
+			var zero T
+			f.complete(zero, err)
+			return
+		}
+		value, err := decode(result)
+		if _, ok := err.(*JsonRpcError); err != nil && !ok {
+			err = prefixPath(err, method+": result")
+		}
+		f.complete(value, err)
+	})
+	return f
+}
+
+// FailedFuture returns a Future that has completed with err.  It is used for
+// calls that fail before they can be queued.
+func FailedFuture[T any](err error) *Future[T] {
+	f := &Future[T]{}
+	var zero T
+	f.complete(zero, err)
+	return f
+}
@@ -0,0 +1,93 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+// batchClient returns a fixed batch response
+type batchClient struct {
+	resps []JsonRpcResponse
+}
+
+func (c batchClient) Call(method string, params ...interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (c batchClient) CallBatch(batch []JsonRpcRequest) []JsonRpcResponse {
+	return c.resps
+}
+
+func TestBatch(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+	svr.AddHandler("B", BImpl{})
+
+	batch := NewBatch(NewServerClient(&svr))
+	f1 := AddCall(batch, "B.echo", []interface{}{"hi"}, DecodeString)
+	f2 := AddCall(batch, "B.echo", []interface{}{10}, DecodeString)
+	f3 := AddCall(batch, "B.echo", []interface{}{"there"}, DecodeString)
+	Equals(t, batch.Len(), 3)
+
+	_, err := f1.Get()
+	Equals(t, err, errBatchNotSent)
+	False(t, f1.Done())
+
+	Equals(t, batch.Send(), nil)
+	True(t, f1.Done())
+
+	s, err := f1.Get()
+	Equals(t, s, "hi")
+	Equals(t, err, nil)
+	s, err = f3.Get()
+	Equals(t, s, "there")
+	Equals(t, err, nil)
+
+	// an invalid call only fails its own Future
+	s, err = f2.Get()
+	Equals(t, s, "")
+	Equals(t, err.(*JsonRpcError).Code, -32602)
+
+	NotEquals(t, batch.Send(), nil)
+}
+
+func TestBatchErrors(t *testing.T) {
+	// the batch as a whole failed
+	rpcErr := &JsonRpcError{Code: -32603, Message: "transport error"}
+	batch := NewBatch(batchClient{[]JsonRpcResponse{{Error: rpcErr}}})
+	f1 := AddCall(batch, "B.echo", []interface{}{"hi"}, DecodeString)
+	f2 := AddCall(batch, "B.echo", []interface{}{"there"}, DecodeString)
+
+	Equals(t, batch.Send(), error(rpcErr))
+	_, err := f1.Get()
+	Equals(t, err, error(rpcErr))
+	_, err = f2.Get()
+	Equals(t, err, error(rpcErr))
+
+	// a response is missing, and a result can't be decoded
+	batch = NewBatch(batchClient{[]JsonRpcResponse{{Id: "2", Result: 10.0}}})
+	f1 = AddCall(batch, "B.echo", []interface{}{"hi"}, DecodeString)
+	f2 = AddCall(batch, "B.echo", []interface{}{"there"}, DecodeString)
+
+	Equals(t, batch.Send(), nil)
+	_, err = f1.Get()
+	Equals(t, err.Error(), "JsonRpcError: code=-32603 message=barrister: B.echo: no response in batch for request id 1")
+	_, err = f2.Get()
+	Equals(t, err.Error(), "barrister: B.echo: result: Unable to convert float64 to string")
+
+	// the path of a nested decode error follows the method
+	batch = NewBatch(batchClient{[]JsonRpcResponse{{Id: "1", Result: map[string]interface{}{"name": 1.0}}}})
+	f4 := AddCall(batch, "B.get", nil, func(res interface{}) (string, error) {
+		s, err := DecodeString(res.(map[string]interface{})["name"])
+		return s, FieldError(err, "name")
+	})
+	Equals(t, batch.Send(), nil)
+	_, err = f4.Get()
+	Equals(t, err.Error(), "barrister: B.get: result.name: Unable to convert float64 to string")
+
+	f3 := FailedFuture[string](rpcErr)
+	True(t, f3.Done())
+	_, err = f3.Get()
+	Equals(t, err, error(rpcErr))
+}
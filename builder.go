@@ -0,0 +1,309 @@
+package barrister
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BarristerVersion is written to the meta element of IDL documents
+// created by Builder and the parser package
+const BarristerVersion = "0.1.6"
+
+// Builder creates an Idl in Go code.  For example:
+//
+//	idl, err := barrister.NewBuilder().
+//	    Comment("User service").
+//	    Namespace("common").
+//	    Enum("Status").Value("ok").Value("err").
+//	    Namespace("").
+//	    Struct("User").Comment("A user of the system").
+//	        Field("id", "string").
+//	        Field("status", "common.Status").
+//	        Field("emails", "[]string [optional]").Comment("most recent first").
+//	    Interface("UserService").
+//	        Function("get", "User [optional]", "id string").Comment("returns null if not found").
+//	    Build()
+//
+// Types are written as in .idl files: a built in type, struct or enum name,
+// with an optional "[]" prefix and "[optional]" suffix.
+//
+// Comment on a StructBuilder, EnumBuilder or InterfaceBuilder applies to the
+// most recently added field, value or function, or to the element itself if
+// no members have been added yet.  Builder.Comment adds a top level comment.
+//
+// Structs and enums added after Namespace(ns) are named "ns.Name", and
+// references to names declared in the same namespace may omit the prefix.
+// Within a namespace, bare names resolve to the namespaced type first.
+type Builder struct {
+	elems []IdlJsonElem
+
+	// namespace that was active when each element was added
+	namespaces []string
+	namespace  string
+	errs       []string
+}
+
+// NewBuilder returns an empty Builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Comment adds a top level comment element
+func (b *Builder) Comment(text string) *Builder {
+	b.add(IdlJsonElem{Type: "comment", Value: text})
+	return b
+}
+
+// Namespace sets the namespace for subsequent structs and enums.  Pass
+// an empty string to stop namespacing elements.
+func (b *Builder) Namespace(ns string) *Builder {
+	b.namespace = ns
+	return b
+}
+
+// Struct adds a struct
+func (b *Builder) Struct(name string) *StructBuilder {
+	b.add(IdlJsonElem{Type: "struct", Name: b.qualify(name), Fields: []Field{}})
+	return &StructBuilder{b, len(b.elems) - 1}
+}
+
+// Enum adds an enum
+func (b *Builder) Enum(name string) *EnumBuilder {
+	b.add(IdlJsonElem{Type: "enum", Name: b.qualify(name), Values: []EnumValue{}})
+	return &EnumBuilder{b, len(b.elems) - 1}
+}
+
+// Interface adds an interface.  Interfaces are never namespaced.
+func (b *Builder) Interface(name string) *InterfaceBuilder {
+	b.add(IdlJsonElem{Type: "interface", Name: name, Functions: []Function{}})
+	return &InterfaceBuilder{b, len(b.elems) - 1}
+}
+
+// Build validates the IDL and returns it.  The IDL includes a meta
+// element with a checksum.
+func (b *Builder) Build() (*Idl, error) {
+	if len(b.errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(b.errs, "\n"))
+	}
+
+	elems := b.qualifiedElems()
+	err := ValidateIdl(elems)
+	if err != nil {
+		return nil, err
+	}
+	return NewIdl(append(elems, NewMetaElem(elems))), nil
+}
+
+// MustBuild calls Build and panics if an error is returned
+func (b *Builder) MustBuild() *Idl {
+	idl, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return idl
+}
+
+// NewMetaElem returns a meta element for elems, with the current time
+// and the checksum of elems
+func NewMetaElem(elems []IdlJsonElem) IdlJsonElem {
+	return IdlJsonElem{
+		Type:             "meta",
+		BarristerVersion: BarristerVersion,
+		DateGenerated:    time.Now().UnixNano() / int64(time.Millisecond),
+		Checksum:         ComputeChecksum(elems),
+	}
+}
+
+func (b *Builder) add(el IdlJsonElem) {
+	b.elems = append(b.elems, el)
+	b.namespaces = append(b.namespaces, b.namespace)
+}
+
+func (b *Builder) qualify(name string) string {
+	if b.namespace == "" {
+		return name
+	}
+	return b.namespace + "." + name
+}
+
+func (b *Builder) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// qualifiedElems returns a copy of the elements with bare references to
+// types declared in the element's namespace qualified
+func (b *Builder) qualifiedElems() []IdlJsonElem {
+	declared := map[string]bool{}
+	for _, el := range b.elems {
+		if el.Type == "struct" || el.Type == "enum" {
+			declared[el.Name] = true
+		}
+	}
+
+	elems := make([]IdlJsonElem, len(b.elems))
+	for i, el := range b.elems {
+		ns := b.namespaces[i]
+		qualify := func(name string) string {
+			if ns != "" && declared[ns+"."+name] {
+				return ns + "." + name
+			}
+			return name
+		}
+
+		el.Fields = copyFields(el.Fields)
+		for x := range el.Fields {
+			el.Fields[x].Type = qualify(el.Fields[x].Type)
+		}
+		el.Extends = qualify(el.Extends)
+		el.Values = copyValues(el.Values)
+		el.Functions = copyFunctions(el.Functions)
+		for x := range el.Functions {
+			fn := &el.Functions[x]
+			for y := range fn.Params {
+				fn.Params[y].Type = qualify(fn.Params[y].Type)
+			}
+			fn.Returns.Type = qualify(fn.Returns.Type)
+		}
+		elems[i] = el
+	}
+	return elems
+}
+
+// StructBuilder adds fields to a struct.  Builder methods may be called
+// on it to add further elements.
+type StructBuilder struct {
+	*Builder
+	pos int
+}
+
+// Extends sets the parent struct
+func (s *StructBuilder) Extends(parent string) *StructBuilder {
+	s.elems[s.pos].Extends = parent
+	return s
+}
+
+// Field adds a field.  typ is an IDL type, e.g. "[]string [optional]"
+func (s *StructBuilder) Field(name string, typ string) *StructBuilder {
+	el := &s.elems[s.pos]
+	f, err := parseTypeSpec(typ)
+	if err != nil {
+		s.errorf("struct %s: field %s: %s", el.Name, name, err)
+	}
+	f.Name = name
+	el.Fields = append(el.Fields, f)
+	return s
+}
+
+// Comment sets the comment of the last field added, or the struct if
+// no fields have been added
+func (s *StructBuilder) Comment(text string) *StructBuilder {
+	el := &s.elems[s.pos]
+	if len(el.Fields) > 0 {
+		el.Fields[len(el.Fields)-1].Comment = text
+	} else {
+		el.Comment = text
+	}
+	return s
+}
+
+// EnumBuilder adds values to an enum.  Builder methods may be called
+// on it to add further elements.
+type EnumBuilder struct {
+	*Builder
+	pos int
+}
+
+// Value adds one or more values
+func (e *EnumBuilder) Value(values ...string) *EnumBuilder {
+	el := &e.elems[e.pos]
+	for _, v := range values {
+		el.Values = append(el.Values, EnumValue{Value: v})
+	}
+	return e
+}
+
+// Comment sets the comment of the last value added, or the enum if
+// no values have been added
+func (e *EnumBuilder) Comment(text string) *EnumBuilder {
+	el := &e.elems[e.pos]
+	if len(el.Values) > 0 {
+		el.Values[len(el.Values)-1].Comment = text
+	} else {
+		el.Comment = text
+	}
+	return e
+}
+
+// InterfaceBuilder adds functions to an interface.  Builder methods may be
+// called on it to add further elements.
+type InterfaceBuilder struct {
+	*Builder
+	pos int
+}
+
+// Function adds a function.  returns is the IDL return type, and each
+// param is a name and IDL type separated by a space.  For example:
+//
+//	Function("calc", "float", "nums []float", "operation MathOp")
+func (i *InterfaceBuilder) Function(name string, returns string, params ...string) *InterfaceBuilder {
+	el := &i.elems[i.pos]
+	fn := Function{Name: name, Params: []Field{}}
+
+	var err error
+	fn.Returns, err = parseTypeSpec(returns)
+	if err != nil {
+		i.errorf("interface %s: function %s returns: %s", el.Name, name, err)
+	}
+
+	for _, p := range params {
+		parts := strings.Fields(p)
+		if len(parts) < 2 {
+			i.errorf("interface %s: function %s: invalid param: %q (expected name and type)", el.Name, name, p)
+			continue
+		}
+		f, err := parseTypeSpec(strings.Join(parts[1:], " "))
+		if err != nil {
+			i.errorf("interface %s: function %s param %s: %s", el.Name, name, parts[0], err)
+		}
+		if f.Optional {
+			i.errorf("interface %s: function %s param %s: params can't be optional", el.Name, name, parts[0])
+		}
+		f.Name = parts[0]
+		fn.Params = append(fn.Params, f)
+	}
+
+	el.Functions = append(el.Functions, fn)
+	return i
+}
+
+// Comment sets the comment of the last function added, or the interface
+// if no functions have been added
+func (i *InterfaceBuilder) Comment(text string) *InterfaceBuilder {
+	el := &i.elems[i.pos]
+	if len(el.Functions) > 0 {
+		el.Functions[len(el.Functions)-1].Comment = text
+	} else {
+		el.Comment = text
+	}
+	return i
+}
+
+// parseTypeSpec parses an IDL type such as "[]string [optional]"
+func parseTypeSpec(spec string) (Field, error) {
+	f := Field{}
+	s := strings.TrimSpace(spec)
+	if strings.HasSuffix(s, "[optional]") {
+		f.Optional = true
+		s = strings.TrimSpace(strings.TrimSuffix(s, "[optional]"))
+	}
+	if strings.HasPrefix(s, "[]") {
+		f.IsArray = true
+		s = s[2:]
+	}
+	if s == "" || strings.ContainsAny(s, " \t[]") {
+		return f, fmt.Errorf("invalid type: %q", spec)
+	}
+	f.Type = s
+	return f, nil
+}
@@ -0,0 +1,149 @@
+package barrister
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func buildUserIdl() (*Idl, error) {
+	return NewBuilder().
+		Comment("User service").
+		Namespace("common").
+		Enum("Status").Value("ok").Value("err").Comment("something went wrong").
+		Struct("Base").Field("id", "string").
+		Namespace("").
+		Struct("User").Comment("A user of the system").
+		Extends("common.Base").
+		Field("status", "common.Status").
+		Field("emails", "[]string [optional]").Comment("most recent first").
+		Interface("UserService").
+		Function("get", "User [optional]", "id string").Comment("returns null if not found").
+		Function("find", "[]User", "ids []string", "status common.Status").
+		Build()
+}
+
+func TestBuilder(t *testing.T) {
+	idl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DeepEquals(t, idl.Comments(), []string{"User service"})
+
+	status, ok := idl.Enum("common.Status")
+	True(t, ok)
+	Equals(t, len(status.Values), 2)
+	Equals(t, status.Values[1].Comment, "something went wrong")
+
+	user, ok := idl.Struct("User")
+	True(t, ok)
+	Equals(t, user.Comment, "A user of the system")
+	Equals(t, user.Extends, "common.Base")
+	Equals(t, len(user.AllFields()), 3)
+	Equals(t, user.Fields[1], Field{Name: "emails", Type: "string", IsArray: true, Optional: true, Comment: "most recent first"})
+
+	base, ok := idl.Struct("common.Base")
+	True(t, ok)
+	Equals(t, base.Fields[0].Type, "string")
+
+	get := idl.Method("UserService.get")
+	Equals(t, get.Comment, "returns null if not found")
+	Equals(t, get.Returns, Field{Type: "User", Optional: true})
+	find := idl.Method("UserService.find")
+	Equals(t, find.Params[1], Field{Name: "status", Type: "common.Status"})
+
+	Equals(t, idl.Meta.BarristerVersion, BarristerVersion)
+	Equals(t, idl.Meta.Checksum, ComputeChecksum(idl.elems))
+	True(t, idl.Meta.DateGenerated > 0)
+}
+
+func TestBuilderQualifiesNamespaceReferences(t *testing.T) {
+	idl := NewBuilder().
+		Namespace("inc").
+		Enum("Status").Value("ok").
+		Struct("Response").Field("status", "Status").
+		Namespace("").
+		Struct("Status").Field("code", "int").
+		Struct("Other").Field("a", "Status").Field("b", "inc.Status").
+		MustBuild()
+
+	resp, _ := idl.Struct("inc.Response")
+	Equals(t, resp.Fields[0].Type, "inc.Status")
+	other, _ := idl.Struct("Other")
+	Equals(t, other.Fields[0].Type, "Status")
+	Equals(t, other.Fields[1].Type, "inc.Status")
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewBuilder().
+		Struct("A").Field("a", "[]").Field("b", "B").
+		Interface("S").Function("f", "int", "x").Function("g", "int", "y int [optional]").
+		Build()
+	Equals(t, err.Error(), strings.Join([]string{
+		`struct A: field a: invalid type: "[]"`,
+		`interface S: function f: invalid param: "x" (expected name and type)`,
+		`interface S: function g param y: params can't be optional`,
+	}, "\n"))
+
+	_, err = NewBuilder().Struct("A").Field("b", "B").Build()
+	Equals(t, err.Error(), "struct A: field b: unknown type: B")
+}
+
+func TestBuilderIdlJson(t *testing.T) {
+	idl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := idl.IdlJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseIdlJson(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	DeepEquals(t, parsed.elems, idl.elems)
+	Equals(t, parsed.Meta, idl.Meta)
+}
+
+func TestIdlSource(t *testing.T) {
+	idl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := idl.IdlSource("users")
+	Equals(t, len(files), 2)
+	Equals(t, string(files["common.idl"]), `namespace common
+
+enum Status {
+    ok
+    // something went wrong
+    err
+}
+
+struct Base {
+    id string
+}
+`)
+	Equals(t, string(files["users.idl"]), `// User service
+
+import "common.idl"
+
+// A user of the system
+struct User extends common.Base {
+    status common.Status
+    // most recent first
+    emails []string [optional]
+}
+
+interface UserService {
+    // returns null if not found
+    get(id string) User [optional]
+    find(ids []string, status common.Status) []User
+}
+`)
+}
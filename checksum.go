@@ -0,0 +1,77 @@
+package barrister
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ComputeChecksum returns a hex encoded MD5 checksum of the given IDL elements.
+//
+// This is a Go-only checksum.  It is not the checksum the Python barrister
+// translator writes to IDL JSON, so the same IDL has a different checksum
+// when it is parsed by the parser package than when it is translated to
+// JSON by the Python tools.  Checksums are only comparable when they were
+// computed by the same toolchain.
+//
+// The checksum ignores comments and the order of elements, struct fields,
+// enum values and interface functions, but detects changes to names, types,
+// extends, parameter order, optional flags and enum values.  Each element is
+// reduced to a tab separated signature, the signatures are sorted, and the
+// JSON encoded list of signatures is hashed.
+func ComputeChecksum(elems []IdlJsonElem) string {
+	sigs := []string{}
+	for _, el := range elems {
+		sig := elemSignature(el)
+		if sig != "" {
+			sigs = append(sigs, sig)
+		}
+	}
+	sort.Strings(sigs)
+
+	b, err := json.Marshal(sigs)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", md5.Sum(b))
+}
+
+func elemSignature(el IdlJsonElem) string {
+	switch el.Type {
+	case "struct":
+		fields := make([]Field, len(el.Fields))
+		copy(fields, el.Fields)
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+		s := ""
+		for _, f := range fields {
+			s += fmt.Sprintf("\t%s\t%s\t%t\t%t", f.Name, f.Type, f.IsArray, f.Optional)
+		}
+		return fmt.Sprintf("struct\t%s\t%s\t%s", el.Name, el.Extends, s)
+	case "enum":
+		vals := make([]string, len(el.Values))
+		for i, v := range el.Values {
+			vals[i] = v.Value
+		}
+		sort.Strings(vals)
+		return "enum\t" + el.Name + "\t" + strings.Join(vals, "\t")
+	case "interface":
+		funcs := make([]Function, len(el.Functions))
+		copy(funcs, el.Functions)
+		sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+
+		s := "interface\t" + el.Name
+		for _, fn := range funcs {
+			s += "[" + fn.Name
+			for _, p := range fn.Params {
+				s += fmt.Sprintf("\t%s\t%t", p.Type, p.IsArray)
+			}
+			r := fn.Returns
+			s += fmt.Sprintf("(%s\t%t\t%t)]", r.Type, r.IsArray, r.Optional)
+		}
+		return s
+	}
+	return ""
+}
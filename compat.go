@@ -0,0 +1,301 @@
+package barrister
+
+import (
+	"fmt"
+)
+
+// ChangeKind identifies the kind of difference between two IDL documents
+type ChangeKind string
+
+const (
+	InterfaceAdded     ChangeKind = "interface_added"
+	InterfaceRemoved   ChangeKind = "interface_removed"
+	FunctionAdded      ChangeKind = "function_added"
+	FunctionRemoved    ChangeKind = "function_removed"
+	ParamAdded         ChangeKind = "param_added"
+	ParamRemoved       ChangeKind = "param_removed"
+	ParamRenamed       ChangeKind = "param_renamed"
+	ParamTypeChanged   ChangeKind = "param_type_changed"
+	ReturnTypeChanged  ChangeKind = "return_type_changed"
+	ReturnMadeOptional ChangeKind = "return_made_optional"
+	ReturnMadeRequired ChangeKind = "return_made_required"
+	StructAdded        ChangeKind = "struct_added"
+	StructRemoved      ChangeKind = "struct_removed"
+	ExtendsChanged     ChangeKind = "extends_changed"
+	FieldAdded         ChangeKind = "field_added"
+	FieldRemoved       ChangeKind = "field_removed"
+	FieldTypeChanged   ChangeKind = "field_type_changed"
+	FieldMadeOptional  ChangeKind = "field_made_optional"
+	FieldMadeRequired  ChangeKind = "field_made_required"
+	EnumAdded          ChangeKind = "enum_added"
+	EnumRemoved        ChangeKind = "enum_removed"
+	EnumValueAdded     ChangeKind = "enum_value_added"
+	EnumValueRemoved   ChangeKind = "enum_value_removed"
+	ElementKindChanged ChangeKind = "element_kind_changed"
+)
+
+// Change is a single difference between two IDL documents
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Element is the qualified name of the changed element.  For example:
+	// "UserService.get" for a function, "UserService.get.id" for a param,
+	// "Person.email" for a field, or "Status.ok" for an enum value
+	Element string `json:"element"`
+
+	// Breaking is true if clients or servers built against the old IDL
+	// may fail when talking to a peer built against the new IDL
+	Breaking bool `json:"breaking"`
+
+	// Human readable description of the change
+	Message string `json:"message"`
+}
+
+func (c Change) String() string {
+	label := "compatible"
+	if c.Breaking {
+		label = "BREAKING"
+	}
+	return fmt.Sprintf("%s: %s: %s", label, c.Element, c.Message)
+}
+
+// CompatReport lists the changes between two IDL documents
+type CompatReport struct {
+	Breaking bool     `json:"breaking"`
+	Changes  []Change `json:"changes"`
+}
+
+// CompareIdl returns the changes required to go from oldIdl to newIdl, and
+// classifies each as breaking or compatible.
+//
+// Because structs may be sent in either direction, struct changes are
+// classified conservatively: only adding an optional field is compatible.
+// Adding interfaces, functions, structs and enums is compatible.  Adding an
+// enum value is breaking if the enum is the type of a struct field or a
+// function result, as old peers reject values that aren't in their IDL.
+// Renaming a param is compatible, as params are sent by position.
+//
+// Changes are ordered by the position of the element in oldIdl, followed by
+// elements only present in newIdl.
+func CompareIdl(oldIdl *Idl, newIdl *Idl) *CompatReport {
+	c := &comparer{oldIdl: oldIdl, newIdl: newIdl, report: &CompatReport{Changes: []Change{}}}
+
+	for _, el := range oldIdl.elems {
+		newEl, ok := findElem(newIdl, el.Name)
+		switch {
+		case el.Type != "struct" && el.Type != "enum" && el.Type != "interface":
+			continue
+		case !ok:
+			c.add(removedKind(el.Type), el.Name, true, "%s removed", el.Type)
+		case newEl.Type != el.Type:
+			c.add(ElementKindChanged, el.Name, true, "changed from %s to %s", el.Type, newEl.Type)
+		case el.Type == "interface":
+			c.compareInterface(el.Name)
+		case el.Type == "struct":
+			c.compareStruct(el, newEl)
+		case el.Type == "enum":
+			c.compareEnum(el, newEl)
+		}
+	}
+
+	for _, el := range newIdl.elems {
+		if el.Type != "struct" && el.Type != "enum" && el.Type != "interface" {
+			continue
+		}
+		if _, ok := findElem(oldIdl, el.Name); !ok {
+			c.add(addedKind(el.Type), el.Name, false, "%s added", el.Type)
+		}
+	}
+
+	return c.report
+}
+
+type comparer struct {
+	oldIdl *Idl
+	newIdl *Idl
+	report *CompatReport
+}
+
+func (c *comparer) add(kind ChangeKind, element string, breaking bool, format string, args ...interface{}) {
+	c.report.Changes = append(c.report.Changes, Change{kind, element, breaking, fmt.Sprintf(format, args...)})
+	if breaking {
+		c.report.Breaking = true
+	}
+}
+
+func (c *comparer) compareInterface(name string) {
+	oldFuncs := c.oldIdl.interfaces[name]
+	newFuncs := c.newIdl.interfaces[name]
+
+	for _, oldFn := range oldFuncs {
+		elem := name + "." + oldFn.Name
+		newFn, ok := c.newIdl.methods[elem]
+		if !ok {
+			c.add(FunctionRemoved, elem, true, "function removed")
+			continue
+		}
+
+		for i, p := range oldFn.Params {
+			pelem := elem + "." + p.Name
+			if i >= len(newFn.Params) {
+				c.add(ParamRemoved, pelem, true, "param removed")
+				continue
+			}
+			np := newFn.Params[i]
+			if !sameType(p, np) {
+				c.add(ParamTypeChanged, pelem, true, "type changed from %s to %s", typeString(p), typeString(np))
+			}
+			if p.Name != np.Name {
+				c.add(ParamRenamed, pelem, false, "renamed to %s", np.Name)
+			}
+		}
+		for i := len(oldFn.Params); i < len(newFn.Params); i++ {
+			c.add(ParamAdded, elem+"."+newFn.Params[i].Name, true, "param added")
+		}
+
+		r, nr := oldFn.Returns, newFn.Returns
+		if !sameType(r, nr) {
+			c.add(ReturnTypeChanged, elem, true, "return type changed from %s to %s", typeString(r), typeString(nr))
+		}
+		if !r.Optional && nr.Optional {
+			c.add(ReturnMadeOptional, elem, true, "return value made optional; old clients may receive null")
+		} else if r.Optional && !nr.Optional {
+			c.add(ReturnMadeRequired, elem, false, "return value made required")
+		}
+	}
+
+	for _, newFn := range newFuncs {
+		elem := name + "." + newFn.Name
+		if _, ok := c.oldIdl.methods[elem]; !ok {
+			c.add(FunctionAdded, elem, false, "function added")
+		}
+	}
+}
+
+func (c *comparer) compareStruct(oldEl IdlJsonElem, newEl IdlJsonElem) {
+	if oldEl.Extends != newEl.Extends {
+		c.add(ExtendsChanged, oldEl.Name, true, "extends changed from %q to %q", oldEl.Extends, newEl.Extends)
+	}
+
+	// compare all fields, including inherited fields, as that's
+	// what is sent on the wire
+	oldFields := c.oldIdl.structs[oldEl.Name].allFields
+	newFields := map[string]Field{}
+	for _, f := range c.newIdl.structs[newEl.Name].allFields {
+		newFields[f.Name] = f
+	}
+
+	for _, f := range oldFields {
+		elem := oldEl.Name + "." + f.Name
+		nf, ok := newFields[f.Name]
+		if !ok {
+			c.add(FieldRemoved, elem, true, "field removed")
+			continue
+		}
+		delete(newFields, f.Name)
+
+		if !sameType(f, nf) {
+			c.add(FieldTypeChanged, elem, true, "type changed from %s to %s", typeString(f), typeString(nf))
+		}
+		if f.Optional && !nf.Optional {
+			c.add(FieldMadeRequired, elem, true, "field made required")
+		} else if !f.Optional && nf.Optional {
+			c.add(FieldMadeOptional, elem, true, "field made optional; old peers may receive null")
+		}
+	}
+
+	for _, f := range c.newIdl.structs[newEl.Name].allFields {
+		if _, ok := newFields[f.Name]; !ok {
+			continue
+		}
+		elem := oldEl.Name + "." + f.Name
+		if f.Optional {
+			c.add(FieldAdded, elem, false, "optional field added")
+		} else {
+			c.add(FieldAdded, elem, true, "required field added")
+		}
+	}
+}
+
+func (c *comparer) compareEnum(oldEl IdlJsonElem, newEl IdlJsonElem) {
+	newVals := map[string]bool{}
+	for _, v := range newEl.Values {
+		newVals[v.Value] = true
+	}
+	oldVals := map[string]bool{}
+	for _, v := range oldEl.Values {
+		oldVals[v.Value] = true
+		if !newVals[v.Value] {
+			c.add(EnumValueRemoved, oldEl.Name+"."+v.Value, true, "enum value removed")
+		}
+	}
+	received := c.enumReceived(newEl.Name)
+	for _, v := range newEl.Values {
+		if !oldVals[v.Value] && received {
+			c.add(EnumValueAdded, oldEl.Name+"."+v.Value, true, "enum value added; old peers reject values they don't know")
+		} else if !oldVals[v.Value] {
+			c.add(EnumValueAdded, oldEl.Name+"."+v.Value, false, "enum value added")
+		}
+	}
+}
+
+// enumReceived returns true if the enum is the type of a struct field or a
+// function result in the new IDL, so its values may be received by peers
+// built against the old IDL
+func (c *comparer) enumReceived(name string) bool {
+	for _, el := range c.newIdl.elems {
+		for _, f := range el.Fields {
+			if f.Type == name {
+				return true
+			}
+		}
+		for _, fn := range el.Functions {
+			if fn.Returns.Type == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findElem returns the struct, enum or interface element with the given name
+func findElem(idl *Idl, name string) (IdlJsonElem, bool) {
+	for _, el := range idl.elems {
+		if el.Name == name && (el.Type == "struct" || el.Type == "enum" || el.Type == "interface") {
+			return el, true
+		}
+	}
+	return IdlJsonElem{}, false
+}
+
+func sameType(a Field, b Field) bool {
+	return a.Type == b.Type && a.IsArray == b.IsArray
+}
+
+// typeString returns the IDL representation of the field type, e.g. "[]string"
+func typeString(f Field) string {
+	if f.IsArray {
+		return "[]" + f.Type
+	}
+	return f.Type
+}
+
+func addedKind(elemType string) ChangeKind {
+	switch elemType {
+	case "interface":
+		return InterfaceAdded
+	case "struct":
+		return StructAdded
+	}
+	return EnumAdded
+}
+
+func removedKind(elemType string) ChangeKind {
+	switch elemType {
+	case "interface":
+		return InterfaceRemoved
+	case "struct":
+		return StructRemoved
+	}
+	return EnumRemoved
+}
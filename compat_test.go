@@ -0,0 +1,152 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func compatIdl(personFields []Field, funcs []Function, statusValues ...string) *Idl {
+	vals := []EnumValue{}
+	for _, v := range statusValues {
+		vals = append(vals, EnumValue{Value: v})
+	}
+	return NewIdl([]IdlJsonElem{
+		{Type: "struct", Name: "Base", Fields: []Field{{Name: "id", Type: "string"}}},
+		{Type: "struct", Name: "Person", Extends: "Base", Fields: personFields},
+		{Type: "enum", Name: "Status", Values: vals},
+		{Type: "interface", Name: "PersonService", Functions: funcs},
+	})
+}
+
+func TestCompareIdlNoChanges(t *testing.T) {
+	idl := MustParseIdlJson(readFile("test/conform.json"))
+	report := CompareIdl(idl, idl)
+	Equals(t, report.Breaking, false)
+	Equals(t, len(report.Changes), 0)
+}
+
+func TestCompareIdl(t *testing.T) {
+	oldIdl := compatIdl(
+		[]Field{
+			{Name: "name", Type: "string"},
+			{Name: "email", Type: "string", Optional: true},
+			{Name: "age", Type: "int"},
+			{Name: "tags", Type: "string", IsArray: true},
+		},
+		[]Function{
+			{Name: "get", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "Person"}},
+			{Name: "find", Params: []Field{{Name: "name", Type: "string"}}, Returns: Field{Type: "Person", IsArray: true}},
+			{Name: "delete", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "bool"}},
+		},
+		"ok", "err")
+
+	newIdl := compatIdl(
+		[]Field{
+			{Name: "name", Type: "string", Optional: true},
+			{Name: "email", Type: "string"},
+			{Name: "tags", Type: "string"},
+			{Name: "nickname", Type: "string", Optional: true},
+			{Name: "created", Type: "int"},
+		},
+		[]Function{
+			{Name: "get", Params: []Field{{Name: "personId", Type: "string"}}, Returns: Field{Type: "Person", Optional: true}},
+			{Name: "find", Params: []Field{{Name: "name", Type: "string"}, {Name: "limit", Type: "int"}},
+				Returns: Field{Type: "Person"}},
+			{Name: "count", Returns: Field{Type: "int"}},
+		},
+		"ok", "unknown")
+
+	expected := []Change{
+		{FieldMadeOptional, "Person.name", true, "field made optional; old peers may receive null"},
+		{FieldMadeRequired, "Person.email", true, "field made required"},
+		{FieldRemoved, "Person.age", true, "field removed"},
+		{FieldTypeChanged, "Person.tags", true, "type changed from []string to string"},
+		{FieldAdded, "Person.nickname", false, "optional field added"},
+		{FieldAdded, "Person.created", true, "required field added"},
+		{EnumValueRemoved, "Status.err", true, "enum value removed"},
+		{EnumValueAdded, "Status.unknown", false, "enum value added"},
+		{ParamRenamed, "PersonService.get.id", false, "renamed to personId"},
+		{ReturnMadeOptional, "PersonService.get", true, "return value made optional; old clients may receive null"},
+		{ParamAdded, "PersonService.find.limit", true, "param added"},
+		{ReturnTypeChanged, "PersonService.find", true, "return type changed from []Person to Person"},
+		{FunctionRemoved, "PersonService.delete", true, "function removed"},
+		{FunctionAdded, "PersonService.count", false, "function added"},
+	}
+
+	report := CompareIdl(oldIdl, newIdl)
+	Equals(t, report.Breaking, true)
+	Equals(t, len(report.Changes), len(expected))
+	for i := 0; i < len(expected) && i < len(report.Changes); i++ {
+		Equals(t, report.Changes[i], expected[i])
+	}
+}
+
+func TestCompareIdlCompatible(t *testing.T) {
+	funcs := []Function{{Name: "get", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "Person", Optional: true}}}
+	oldIdl := compatIdl([]Field{{Name: "name", Type: "string"}}, funcs, "ok")
+
+	newFuncs := []Function{
+		{Name: "get", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "Person"}},
+		{Name: "put", Params: []Field{{Name: "p", Type: "Person"}}, Returns: Field{Type: "bool"}},
+	}
+	newIdl := compatIdl([]Field{{Name: "name", Type: "string"}, {Name: "age", Type: "int", Optional: true}}, newFuncs, "ok", "err")
+
+	report := CompareIdl(oldIdl, newIdl)
+	Equals(t, report.Breaking, false)
+	Equals(t, len(report.Changes), 4)
+}
+
+func TestCompareIdlEnumValueAdded(t *testing.T) {
+	get := Function{Name: "get", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "Person"}}
+	tests := []struct {
+		fields   []Field
+		funcs    []Function
+		breaking bool
+	}{
+		// unused
+		{[]Field{{Name: "name", Type: "string"}}, []Function{get}, false},
+		// only sent as a param
+		{[]Field{{Name: "name", Type: "string"}}, []Function{get,
+			{Name: "find", Params: []Field{{Name: "s", Type: "Status"}}, Returns: Field{Type: "Person", IsArray: true}}}, false},
+		// struct field
+		{[]Field{{Name: "name", Type: "string"}, {Name: "status", Type: "Status", Optional: true}}, []Function{get}, true},
+		// function result
+		{[]Field{{Name: "name", Type: "string"}}, []Function{get,
+			{Name: "status", Params: []Field{{Name: "id", Type: "string"}}, Returns: Field{Type: "Status", IsArray: true}}}, true},
+	}
+	for i, test := range tests {
+		report := CompareIdl(compatIdl(test.fields, test.funcs, "ok"), compatIdl(test.fields, test.funcs, "ok", "err"))
+		Equals(t, len(report.Changes), 1)
+		Equals(t, report.Changes[0].Kind, EnumValueAdded)
+		if report.Changes[0].Breaking != test.breaking {
+			t.Errorf("test %d: breaking is %v, expected %v", i, report.Changes[0].Breaking, test.breaking)
+		}
+		Equals(t, report.Breaking, test.breaking)
+	}
+}
+
+func TestCompareIdlElements(t *testing.T) {
+	oldIdl := NewIdl([]IdlJsonElem{
+		{Type: "struct", Name: "A", Fields: []Field{{Name: "a", Type: "string"}}},
+		{Type: "struct", Name: "B", Extends: "A"},
+		{Type: "enum", Name: "C", Values: []EnumValue{{Value: "c"}}},
+		{Type: "interface", Name: "D"},
+	})
+	newIdl := NewIdl([]IdlJsonElem{
+		{Type: "struct", Name: "A", Fields: []Field{{Name: "a", Type: "string"}}},
+		{Type: "struct", Name: "B", Fields: []Field{{Name: "a", Type: "string"}}},
+		{Type: "struct", Name: "C", Fields: []Field{{Name: "c", Type: "string"}}},
+		{Type: "interface", Name: "E"},
+	})
+
+	report := CompareIdl(oldIdl, newIdl)
+	Equals(t, len(report.Changes), 4)
+	Equals(t, report.Changes[0].Kind, ExtendsChanged)
+	Equals(t, report.Changes[0].Element, "B")
+	Equals(t, report.Changes[1].Kind, ElementKindChanged)
+	Equals(t, report.Changes[1].Message, "changed from enum to struct")
+	Equals(t, report.Changes[2].Kind, InterfaceRemoved)
+	Equals(t, report.Changes[3].Kind, InterfaceAdded)
+	Equals(t, report.Changes[3].Element, "E")
+}
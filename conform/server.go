@@ -50,8 +50,8 @@ func (i AImpl) Sqrt(a float64) (float64, error) {
 func (a AImpl) Repeat(req1 RepeatRequest) (RepeatResponse, error) {
 	rr := RepeatResponse{inc.Response{"ok"}, req1.Count, []string{}}
 
-	s := req1.To_repeat
-	if req1.Force_uppercase {
+	s := req1.ToRepeat
+	if req1.ForceUppercase {
 		s = strings.ToUpper(s)
 	}
 	for i := int64(0); i < req1.Count; i++ {
@@ -64,12 +64,12 @@ func (a AImpl) Repeat(req1 RepeatRequest) (RepeatResponse, error) {
 //
 // returns a result with:
 //   hi="hi" and status="ok"
-func (a AImpl) Say_hi() (HiResponse, error) {
+func (a AImpl) SayHi() (HiResponse, error) {
 	return HiResponse{"hi"}, nil
 }
 
 // returns num as an array repeated 'count' number of times
-func (a AImpl) Repeat_num(num int64, count int64) ([]int64, error) {
+func (a AImpl) RepeatNum(num int64, count int64) ([]int64, error) {
 	arr := []int64{}
 	for i := int64(0); i < count; i++ {
 		arr = append(arr, num)
@@ -82,7 +82,7 @@ func (a AImpl) Repeat_num(num int64, count int64) ([]int64, error) {
 // we use this to test the '[optional]' enforcement, 
 // as we invoke it with a null email
 func (a AImpl) PutPerson(p Person) (string, error) {
-	return p.PersonId, nil
+	return p.PersonID, nil
 }
 
 type BImpl struct{}
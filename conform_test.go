@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -487,6 +488,60 @@ func TestServerBarristerIdl(t *testing.T) {
 	DeepEquals(t, idl.elems, rpcResp.Result)
 }
 
+// CheckedStatus marshals like a generated enum, so its zero value can't be
+// marshaled
+type CheckedStatus string
+
+func (e CheckedStatus) MarshalJSON() ([]byte, error) {
+	if e != "ok" && e != "err" {
+		return nil, fmt.Errorf("invalid Status value: %q", string(e))
+	}
+	return json.Marshal(string(e))
+}
+
+type CheckedRepeatResponse struct {
+	Status CheckedStatus `json:"status"`
+	Count  int           `json:"count"`
+	Items  []string      `json:"items"`
+}
+
+type AImpl_ZeroEnum struct {
+	AImpl
+}
+
+func (i AImpl_ZeroEnum) Repeat(req1 RepeatRequest) (CheckedRepeatResponse, error) {
+	return CheckedRepeatResponse{}, nil
+}
+
+func TestServerInvokeMarshalError(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+	svr.AddHandler("A", AImpl_ZeroEnum{})
+	headers := newHeaders()
+
+	repeat := `{"jsonrpc":"2.0","id":"1","method":"A.repeat","params":[{"to_repeat":"a","count":1,"force_uppercase":false}]}`
+	resp := JsonRpcResponse{}
+	err := json.Unmarshal(svr.InvokeBytes(headers, []byte(repeat)), &resp)
+	Equals(t, err, nil)
+	Equals(t, resp.Id, "1")
+	if resp.Error == nil {
+		t.Fatalf("A.repeat with a zero enum result didn't return an error")
+	}
+	Equals(t, resp.Error.Code, -32603)
+	True(t, strings.Contains(resp.Error.Message, `invalid Status value: ""`))
+
+	// only the response that can't be marshaled is replaced in a batch
+	add := `{"jsonrpc":"2.0","id":"2","method":"A.add","params":[1,2]}`
+	batch := []JsonRpcResponse{}
+	err = json.Unmarshal(svr.InvokeBytes(headers, []byte("["+repeat+","+add+"]")), &batch)
+	Equals(t, err, nil)
+	Equals(t, len(batch), 2)
+	Equals(t, batch[0].Error.Code, -32603)
+	Equals(t, batch[1].Id, "2")
+	Equals(t, batch[1].Error, (*JsonRpcError)(nil))
+	Equals(t, batch[1].Result, 3.0)
+}
+
 type ProxyFilter struct {
 	pre  func(r *RequestResponse) bool
 	post func(r *RequestResponse) bool
@@ -653,3 +708,92 @@ func newHeaders() Headers {
 		Response: make(map[string][]string),
 	}
 }
+
+// bDispatcher is written the way idl2go -dispatch generates a Dispatcher
+type bDispatcher struct {
+	calls *int
+}
+
+func (d bDispatcher) Dispatch(idl *Idl, handler interface{}, function string, params []interface{}) (interface{}, error) {
+	*d.calls++
+	h, ok := handler.(BImpl)
+	if !ok {
+		return nil, HandlerError("B", handler)
+	}
+	switch function {
+	case "echo":
+		s, err := DecodeString(params[0])
+		if err != nil {
+			return nil, ParamError(err, 0)
+		}
+		return h.Echo(s)
+	}
+	return nil, &JsonRpcError{Code: -32601, Message: "Unsupported method: B." + function}
+}
+
+func TestServerDispatcher(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+	calls := 0
+	svr.AddDispatcher("B", BImpl{context: &Context{}}, bDispatcher{&calls})
+
+	preCount := 0
+	postCount := 0
+	pre := func(r *RequestResponse) bool {
+		preCount++
+		r.Handler.(BImpl).context.UserId = 100
+		return true
+	}
+	post := func(r *RequestResponse) bool {
+		postCount++
+		return true
+	}
+	svr.AddFilter(ProxyFilter{pre, post})
+
+	headers := newHeaders()
+
+	// the handler is cloned, and the clone is modified by the filter
+	r := resultOk(svr.Call(headers, "B.echo", "get-userid"))
+	s, ok := r.(*string)
+	True(t, ok)
+	Equals(t, *s, "100")
+	Equals(t, calls, 1)
+	Equals(t, preCount, 1)
+	Equals(t, postCount, 1)
+
+	// invalid params are -32602 errors, and PostInvoke isn't called
+	_, err := svr.Call(headers, "B.echo", 10)
+	DeepEquals(t, err, &JsonRpcError{Code: -32602, Message: "barrister: param[0]: Unable to convert int to string"})
+	Equals(t, calls, 2)
+	Equals(t, postCount, 1)
+
+	// the number of params is checked before Dispatch is called
+	_, err = svr.Call(headers, "B.echo")
+	Equals(t, err.(*JsonRpcError).Code, -32602)
+	_, err = svr.Call(headers, "B.foo", "x")
+	Equals(t, err.(*JsonRpcError).Code, -32601)
+	Equals(t, calls, 2)
+}
+
+func TestAddDispatcherPanicsIfIfaceNotInIdl(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("AddDispatcher didn't panic when called w/invalid iface name")
+		}
+	}()
+	svr.AddDispatcher("C", BImpl{}, bDispatcher{new(int)})
+}
+
+func TestAddDispatcherPanicsIfDispatcherNil(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+
+	defer func() {
+		r := recover()
+		Equals(t, r, "barrister: nil Dispatcher for interface: B")
+	}()
+	svr.AddDispatcher("B", BImpl{}, nil)
+}
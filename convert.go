@@ -3,6 +3,7 @@ package barrister
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -36,10 +37,21 @@ type convert struct {
 	actual    interface{}
 	converted reflect.Value
 	path      string
+
+	// if true, values of mapped types are only checked for IDL
+	// compatibility and not decoded (used by Server.AddHandler)
+	checkOnly bool
 }
 
 func newConvert(idl *Idl, field *Field, desired reflect.Type, actual interface{}, path string) *convert {
-	return &convert{idl, field, desired, false, actual, zeroVal, path}
+	return &convert{idl, field, desired, false, actual, zeroVal, path, false}
+}
+
+// child returns a convert for a nested value that inherits the settings of c
+func (c *convert) child(field *Field, desired reflect.Type, actual interface{}, path string) *convert {
+	conv := newConvert(c.idl, field, desired, actual, path)
+	conv.checkOnly = c.checkOnly
+	return conv
 }
 
 func (c *convert) run() (reflect.Value, error) {
@@ -55,6 +67,10 @@ func (c *convert) run() (reflect.Value, error) {
 	if actType == nil {
 		if c.field.Optional && (desiredKind == reflect.Ptr || desiredKind == reflect.Slice) {
 			return reflect.ValueOf(c.actual), nil
+		} else if c.field.Optional && isOptionalType(c.desired) {
+			opt := reflect.New(c.desired).Elem()
+			opt.Field(2).SetBool(true)
+			return opt, nil
 		} else if c.field.Optional {
 			return reflect.Zero(c.desired), nil
 		} else {
@@ -62,12 +78,31 @@ func (c *convert) run() (reflect.Value, error) {
 		}
 	}
 
+	if isOptionalType(c.desired) {
+		return c.convertOptional()
+	}
+
 	if desiredKind == reflect.Ptr {
 		c.desirePtr = true
 		c.desired = c.desired.Elem()
 		desiredKind = c.desired.Kind()
 	}
 
+	mapping := c.idl.typeRegistry().forGoType(c.desired)
+	if mapping != nil {
+		err := c.checkMapping(mapping)
+		if err != nil {
+			return zeroVal, err
+		}
+		if c.checkOnly {
+			c.converted = reflect.New(c.desired)
+			return c.convertedVal()
+		}
+		if mapping.Decode != nil {
+			return c.decodeMapped(mapping)
+		}
+	}
+
 	c.converted = reflect.New(c.desired)
 
 	actVal := reflect.ValueOf(c.actual)
@@ -78,7 +113,7 @@ func (c *convert) run() (reflect.Value, error) {
 		switch desiredKind {
 		case reflect.String:
 			if c.field.Type == "string" {
-				c.converted.Elem().Set(actVal)
+				c.converted.Elem().SetString(actVal.String())
 				return c.returnVal("string")
 			} else {
 				enum, ok := c.idl.enums[c.field.Type]
@@ -197,6 +232,21 @@ func (c *convert) run() (reflect.Value, error) {
 	return zeroVal, &typeError{c.path, msg}
 }
 
+// convertOptional converts a non-null value to a set Optional
+func (c *convert) convertOptional() (reflect.Value, error) {
+	if !c.field.Optional {
+		return zeroVal, &typeError{c.path, fmt.Sprintf("%v is not optional, so it can't be converted to %v", c.field.Type, c.desired)}
+	}
+	val, err := c.child(c.field, c.desired.Field(0).Type, c.actual, c.path).run()
+	if err != nil {
+		return zeroVal, err
+	}
+	opt := reflect.New(c.desired).Elem()
+	opt.Field(0).Set(val)
+	opt.Field(1).SetBool(true)
+	return opt, nil
+}
+
 func (c *convert) convertSlice(actVal reflect.Value) (reflect.Value, error) {
 	length := actVal.Len()
 	slice := reflect.MakeSlice(c.desired, length, length)
@@ -206,14 +256,14 @@ func (c *convert) convertSlice(actVal reflect.Value) (reflect.Value, error) {
 
 	sliceType := c.desired.Elem()
 
-	elemConv := newConvert(c.idl, elemField, sliceType, nil, "")
+	elemConv := c.child(elemField, sliceType, nil, "")
 
 	for x := 0; x < length; x++ {
 
 		el := actVal.Index(x)
 		elemConv.actual = el.Interface()
 
-		elemConv.path = c.path + "[" + string(x) + "]"
+		elemConv.path = c.path + "[" + strconv.Itoa(x) + "]"
 
 		conv, err := elemConv.run()
 		if err != nil {
@@ -236,22 +286,15 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 		return zeroVal, &typeError{path: c.path, msg: msg}
 	}
 
+	fields := resolveStructFields(c.desired, idlStruct)
+	if fields.err != nil {
+		return zeroVal, &typeError{path: c.path, msg: fields.err.Error()}
+	}
+
 	val := reflect.New(c.desired)
 
-	for _, sField := range idlStruct.allFields {
+	for x, sField := range idlStruct.allFields {
 		fname := sField.Name
-		goName := fname
-		structField, ok := c.desired.FieldByName(fname)
-		if !ok {
-			goName = capitalize(fname)
-			structField, ok = c.desired.FieldByName(goName)
-			if !ok {
-				msg := fmt.Sprintf("Struct: %v is missing required field: %s",
-					c.desired, goName)
-				return zeroVal, &typeError{path: c.path, msg: msg}
-			}
-		}
-
 		mval, ok := m[fname]
 
 		if !ok && !sField.Optional {
@@ -261,21 +304,15 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 		}
 
 		if ok {
+			f := fieldByIndex(val.Elem(), fields.index[x])
 
-			fieldConv := newConvert(c.idl, &sField, structField.Type, mval,
+			fieldConv := c.child(&sField, f.Type(), mval,
 				c.path+"."+fname)
 			conv, err := fieldConv.run()
 			if err != nil {
 				return zeroVal, err
 			}
 
-			f := val.Elem().FieldByName(goName)
-			if f == zeroVal {
-				msg := fmt.Sprintf("Instance: %s is missing required field: %s",
-					c.field.Type, goName)
-				return zeroVal, &typeError{path: c.path, msg: msg}
-			}
-
 			if f.Kind() == reflect.Ptr {
 				if conv.Kind() == reflect.Ptr {
 					f.Set(conv)
@@ -296,6 +333,44 @@ func (c *convert) convertStruct(m map[string]interface{}) (reflect.Value, error)
 	return c.convertedVal()
 }
 
+// idlScalarType returns the IDL scalar type that values of c.field are
+// serialized as.  Enums are serialized as strings.
+func (c *convert) idlScalarType() string {
+	_, isEnum := c.idl.enums[c.field.Type]
+	if isEnum {
+		return "string"
+	}
+	return c.field.Type
+}
+
+func (c *convert) checkMapping(m *TypeMapping) error {
+	idlType := c.idlScalarType()
+	if idlType != m.IdlType {
+		msg := fmt.Sprintf("Type mismatch for '%s' - %v is mapped to IDL type: %s but field has type: %s",
+			c.path, c.desired, m.IdlType, c.field.Type)
+		return &typeError{path: c.path, msg: msg}
+	}
+	return nil
+}
+
+func (c *convert) decodeMapped(m *TypeMapping) (reflect.Value, error) {
+	out, err := m.Decode(c.actual)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to decode %v as %v: %s", c.actual, c.desired, err)
+		return zeroVal, &typeError{path: c.path, msg: msg}
+	}
+
+	outVal := reflect.ValueOf(out)
+	if !outVal.IsValid() || !outVal.Type().AssignableTo(c.desired) {
+		msg := fmt.Sprintf("TypeMapping Decode for %v returned invalid type: %T", c.desired, out)
+		return zeroVal, &typeError{path: c.path, msg: msg}
+	}
+
+	c.converted = reflect.New(c.desired)
+	c.converted.Elem().Set(outVal)
+	return c.convertedVal()
+}
+
 func (c *convert) returnVal(convertedType string) (reflect.Value, error) {
 	if c.field.Type != convertedType {
 		msg := fmt.Sprintf("Type mismatch for '%s' - Expected: %s Got: %v",
@@ -323,13 +398,16 @@ func capitalize(s string) string {
 	return strings.ToUpper(s[0:1]) + s[1:]
 }
 
-func capitalizeAndStripMatchingPkg(s string, pkgToStrip string) string {
+// goNameStripMatchingPkg converts the IDL type name s to a Go type name using the
+// given NamingStrategy.  If s is namespaced and the namespace matches pkgToStrip,
+// the namespace is removed.
+func goNameStripMatchingPkg(s string, pkgToStrip string, naming NamingStrategy) string {
 	ns, name := splitNs(s)
 	if ns == "" {
-		return capitalize(s)
+		return naming(s)
 	} else if ns == pkgToStrip {
-		return capitalize(name)
+		return naming(name)
 	}
 
-	return ns + "." + capitalize(name)
+	return ns + "." + naming(name)
 }
@@ -0,0 +1,176 @@
+package barrister
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IdlUnmarshaler is implemented by the structs and enums generated by idl2go.
+// UnmarshalIdl sets the receiver from a value decoded by a Serializer (a
+// map[string]interface{} for structs, a string for enums) and checks it
+// against the IDL, without using reflection.
+//
+// Errors returned by UnmarshalIdl describe the location of the invalid value
+// relative to v.  Use FieldError, ElemError and ParamError to add the
+// location of v itself.
+type IdlUnmarshaler interface {
+	UnmarshalIdl(v interface{}) error
+}
+
+// The Decode functions convert a value decoded by a Serializer to a Go type,
+// accepting the same values as Convert.  They are used by idl2go generated
+// code.
+
+// DecodeString returns v as a string
+func DecodeString(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", decodeError(v, "string")
+	}
+	return s, nil
+}
+
+// DecodeInt returns v as an int64.  Floats are accepted if they have no
+// fractional part, as JSON numbers are decoded as float64.
+func DecodeInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case float64:
+		i := int64(n)
+		if float64(i) == n {
+			return i, nil
+		}
+	}
+	return 0, decodeError(v, "int")
+}
+
+// DecodeFloat returns v as a float64
+func DecodeFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	}
+	return 0, decodeError(v, "float")
+}
+
+// DecodeBool returns v as a bool
+func DecodeBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, decodeError(v, "bool")
+	}
+	return b, nil
+}
+
+// DecodeArray returns v as a slice, whose elements are still to be decoded
+func DecodeArray(v interface{}) ([]interface{}, error) {
+	a, ok := v.([]interface{})
+	if !ok {
+		return nil, decodeError(v, "array")
+	}
+	return a, nil
+}
+
+// DecodeObject returns v as a map, whose values are still to be decoded
+func DecodeObject(v interface{}) (map[string]interface{}, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, decodeError(v, "struct")
+	}
+	return m, nil
+}
+
+// DecodeEnum returns v as a string, which must be one of values
+func DecodeEnum(v interface{}, values []string) (string, error) {
+	s, err := DecodeString(v)
+	if err != nil {
+		return "", err
+	}
+	for _, val := range values {
+		if s == val {
+			return s, nil
+		}
+	}
+
+	msg := fmt.Sprintf("Value '%s' not in enum values: ", s)
+	for x, val := range values {
+		if x > 0 {
+			msg += ", "
+		}
+		msg += "'" + val + "'"
+	}
+	return "", &typeError{msg: msg}
+}
+
+// FieldValue returns the value of the named field of a struct.  ok is false
+// if the field is optional and is missing or null.  An error is returned if
+// a required field is missing or null.
+func FieldValue(m map[string]interface{}, name string, optional bool) (v interface{}, ok bool, err error) {
+	v, ok = m[name]
+	if ok && v != nil {
+		return v, true, nil
+	}
+	if optional {
+		return nil, false, nil
+	}
+	if ok {
+		return nil, false, &typeError{path: "." + name, msg: "null not allowed"}
+	}
+	return nil, false, &typeError{msg: "missing required field: " + name}
+}
+
+// FieldError adds the struct field name to the location of err
+func FieldError(err error, name string) error {
+	return prefixPath(err, "."+name)
+}
+
+// ElemError adds the array index i to the location of err
+func ElemError(err error, i int) error {
+	return prefixPath(err, "["+strconv.Itoa(i)+"]")
+}
+
+// ParamError adds the location of param i to err.  A Dispatcher returns
+// this error if param i is invalid, and Server.Call returns it as a
+// JsonRpcError with code -32602 without calling the PostInvoke filters.
+func ParamError(err error, i int) error {
+	err = prefixPath(err, "param["+strconv.Itoa(i)+"]")
+	return &invalidParams{JsonRpcError{Code: -32602, Message: err.Error()}}
+}
+
+// invalidParams is returned by ParamError
+type invalidParams struct {
+	JsonRpcError
+}
+
+// HandlerError returns the error a generated Dispatcher returns if the
+// handler doesn't implement the interface iface
+func HandlerError(iface string, handler interface{}) error {
+	msg := fmt.Sprintf("barrister: handler %T does not implement %s", handler, iface)
+	return &JsonRpcError{Code: -32603, Message: msg}
+}
+
+func prefixPath(err error, prefix string) error {
+	te, ok := err.(*typeError)
+	if !ok {
+		return &typeError{path: prefix, msg: err.Error()}
+	}
+	return &typeError{path: prefix + te.path, msg: te.msg}
+}
+
+func decodeError(v interface{}, idlType string) error {
+	if v == nil {
+		return &typeError{msg: "null not allowed"}
+	}
+	return &typeError{msg: fmt.Sprintf("Unable to convert %T to %s", v, idlType)}
+}
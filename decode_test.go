@@ -0,0 +1,150 @@
+package barrister
+
+import (
+	"io/ioutil"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestDecodeInt(t *testing.T) {
+	for _, v := range []interface{}{int64(3), 3, float64(3)} {
+		i, err := DecodeInt(v)
+		Equals(t, err, nil)
+		Equals(t, i, int64(3))
+	}
+
+	_, err := DecodeInt(3.5)
+	Equals(t, err.Error(), "barrister: : Unable to convert float64 to int")
+	_, err = DecodeInt(nil)
+	Equals(t, err.Error(), "barrister: : null not allowed")
+}
+
+func TestDecodeEnum(t *testing.T) {
+	s, err := DecodeEnum("b", []string{"a", "b"})
+	Equals(t, err, nil)
+	Equals(t, s, "b")
+
+	_, err = DecodeEnum("c", []string{"a", "b"})
+	Equals(t, err.Error(), "barrister: : Value 'c' not in enum values: 'a', 'b'")
+}
+
+func TestFieldValue(t *testing.T) {
+	m := map[string]interface{}{"a": "x", "b": nil}
+
+	v, ok, err := FieldValue(m, "a", false)
+	Equals(t, v, "x")
+	True(t, ok)
+	Equals(t, err, nil)
+
+	for _, name := range []string{"b", "c"} {
+		_, ok, err = FieldValue(m, name, true)
+		False(t, ok)
+		Equals(t, err, nil)
+	}
+
+	_, _, err = FieldValue(m, "b", false)
+	Equals(t, err.Error(), "barrister: .b: null not allowed")
+	_, _, err = FieldValue(m, "c", false)
+	Equals(t, err.Error(), "barrister: : missing required field: c")
+}
+
+func TestDecodeErrorPath(t *testing.T) {
+	_, err := DecodeString(1.5)
+	err = ElemError(FieldError(err, "names"), 2)
+	Equals(t, err.Error(), "barrister: [2].names: Unable to convert float64 to string")
+
+	err = ParamError(err, 1)
+	DeepEquals(t, err, &invalidParams{JsonRpcError{Code: -32602,
+		Message: "barrister: param[1][2].names: Unable to convert float64 to string"}})
+}
+
+// decodeRepeatResponse is RepeatResponse with the UnmarshalIdl method idl2go
+// generates for it
+type decodeRepeatResponse struct {
+	Status Status
+	Count  int64
+	Items  []string
+}
+
+func (s *decodeRepeatResponse) UnmarshalIdl(v interface{}) error {
+	if x, ok := v.(decodeRepeatResponse); ok {
+		*s = x
+		return nil
+	}
+	m, err := DecodeObject(v)
+	if err != nil {
+		return err
+	}
+	if _v, ok, err := FieldValue(m, "status", false); err != nil {
+		return err
+	} else if ok {
+		if _x, _err := DecodeEnum(_v, []string{"ok", "err"}); _err != nil {
+			return FieldError(_err, "status")
+		} else {
+			s.Status = Status(_x)
+		}
+	}
+	if _v, ok, err := FieldValue(m, "count", false); err != nil {
+		return err
+	} else if ok {
+		if _x, _err := DecodeInt(_v); _err != nil {
+			return FieldError(_err, "count")
+		} else {
+			s.Count = _x
+		}
+	}
+	if _v, ok, err := FieldValue(m, "items", false); err != nil {
+		return err
+	} else if ok {
+		if _a, _err := DecodeArray(_v); _err != nil {
+			return FieldError(_err, "items")
+		} else {
+			_s := make([]string, len(_a))
+			for _i, _e := range _a {
+				if _x, _err := DecodeString(_e); _err != nil {
+					return FieldError(ElemError(_err, _i), "items")
+				} else {
+					_s[_i] = _x
+				}
+			}
+			s.Items = _s
+		}
+	}
+	return nil
+}
+
+func repeatResult() interface{} {
+	return map[string]interface{}{
+		"status": "ok",
+		"count":  float64(3),
+		"items":  []interface{}{"a", "a", "a"},
+	}
+}
+
+func TestUnmarshalIdl(t *testing.T) {
+	var r decodeRepeatResponse
+	err := r.UnmarshalIdl(repeatResult())
+	Equals(t, err, nil)
+	DeepEquals(t, r, decodeRepeatResponse{StatusOk, 3, []string{"a", "a", "a"}})
+
+	bad := repeatResult()
+	bad.(map[string]interface{})["items"] = []interface{}{"a", 1.5}
+	err = r.UnmarshalIdl(bad)
+	Equals(t, err.Error(), "barrister: .items[1]: Unable to convert float64 to string")
+}
+
+// BenchmarkParseIdl is the cost each generated NewXxxProxy call paid before
+// the parsed IDL was shared
+func BenchmarkParseIdl(b *testing.B) {
+	data, err := ioutil.ReadFile("test/conform.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		MustParseIdlJson(data)
+	}
+}
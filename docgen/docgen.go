@@ -0,0 +1,260 @@
+// Package docgen generates HTML or Markdown documentation from an IDL.
+//
+// The generated site contains an index page listing the interfaces and
+// types, one page per interface, and a types page describing every struct
+// and enum.  Type names link to their definitions, struct pages show the
+// inheritance tree, and each function has an example JSON-RPC request and
+// response.
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+const (
+	HTML     = "html"
+	Markdown = "markdown"
+)
+
+// Options control the generated documentation
+type Options struct {
+	// Title of the index page.  If empty, "API Documentation" is used.
+	Title string
+
+	// Format is HTML or Markdown.  If empty, HTML is used.
+	Format string
+}
+
+// Generate returns the documentation files for idl, keyed by file name
+func Generate(idl *barrister.Idl, opts Options) (map[string][]byte, error) {
+	if opts.Title == "" {
+		opts.Title = "API Documentation"
+	}
+	if opts.Format == "" {
+		opts.Format = HTML
+	}
+
+	var ext string
+	switch opts.Format {
+	case HTML:
+		ext = ".html"
+	case Markdown:
+		ext = ".md"
+	default:
+		return nil, fmt.Errorf("docgen: unknown format: %s", opts.Format)
+	}
+
+	s := newSite(idl, opts.Title, ext)
+	tmpl := newTemplates(opts.Format, ext)
+
+	files := map[string][]byte{}
+	render := func(fname string, name string, data interface{}) error {
+		b := &bytes.Buffer{}
+		err := tmpl.ExecuteTemplate(b, name, data)
+		if err != nil {
+			return err
+		}
+		files[fname] = b.Bytes()
+		return nil
+	}
+
+	err := render("index"+ext, "index", s)
+	if err != nil {
+		return nil, err
+	}
+	err = render("types"+ext, "types", s)
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range s.Interfaces {
+		err = render(iface.File, "interface", pageData{s, iface})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// site is the data passed to the templates
+type site struct {
+	Title      string
+	Comments   []string
+	Interfaces []ifaceView
+	Structs    []structView
+	Enums      []enumView
+
+	// inheritance tree of all structs
+	Tree []treeNode
+
+	ext string
+}
+
+// pageData is passed to the interface template
+type pageData struct {
+	Site  *site
+	Iface ifaceView
+}
+
+type ifaceView struct {
+	Name      string
+	Comment   string
+	File      string
+	Functions []funcView
+}
+
+type funcView struct {
+	Name     string
+	Method   string
+	Comment  string
+	Params   []fieldView
+	Returns  fieldView
+	Request  string
+	Response string
+}
+
+type fieldView struct {
+	Name     string
+	Comment  string
+	Type     typeRef
+	Optional bool
+
+	// name of the struct the field is inherited from, if any
+	InheritedFrom string
+}
+
+// typeRef is a reference to an IDL type.  Link is empty for built in types.
+type typeRef struct {
+	Name    string
+	IsArray bool
+	Link    string
+}
+
+type structView struct {
+	Name    string
+	Comment string
+
+	// Ancestors from the root of the extends chain to the parent
+	Ancestors []typeRef
+
+	// Subtypes are the structs that directly extend this struct
+	Subtypes []typeRef
+
+	Fields []fieldView
+}
+
+type enumView struct {
+	Name    string
+	Comment string
+	Values  []barrister.EnumValue
+}
+
+type treeNode struct {
+	Type     typeRef
+	Children []treeNode
+}
+
+func newSite(idl *barrister.Idl, title string, ext string) *site {
+	s := &site{Title: title, Comments: idl.Comments(), ext: ext}
+
+	for _, iface := range idl.Interfaces() {
+		iv := ifaceView{Name: iface.Name, Comment: iface.Comment, File: iface.Name + ext}
+		for _, fn := range iface.Functions {
+			method := iface.Name + "." + fn.Name
+			fv := funcView{Name: fn.Name, Method: method, Comment: fn.Comment, Returns: s.fieldView(idl, fn.Returns, "")}
+			for _, p := range fn.Params {
+				fv.Params = append(fv.Params, s.fieldView(idl, p, ""))
+			}
+			fv.Request, fv.Response = exampleRequest(idl, method, fn)
+			iv.Functions = append(iv.Functions, fv)
+		}
+		s.Interfaces = append(s.Interfaces, iv)
+	}
+
+	children := map[string][]string{}
+	roots := []string{}
+	for _, st := range idl.Structs() {
+		if st.Extends == "" {
+			roots = append(roots, st.Name)
+		} else {
+			children[st.Extends] = append(children[st.Extends], st.Name)
+		}
+	}
+
+	for _, st := range idl.Structs() {
+		sv := structView{Name: st.Name, Comment: st.Comment}
+
+		for parent := st.Extends; parent != ""; {
+			sv.Ancestors = append([]typeRef{s.typeRef(idl, parent, false)}, sv.Ancestors...)
+			p, ok := idl.Struct(parent)
+			if !ok || len(sv.Ancestors) > len(idl.Structs()) {
+				break
+			}
+			parent = p.Extends
+		}
+		for _, child := range children[st.Name] {
+			sv.Subtypes = append(sv.Subtypes, s.typeRef(idl, child, false))
+		}
+
+		// inherited fields come first, so owner is the struct that
+		// declares each field
+		owners := []string{}
+		for _, a := range sv.Ancestors {
+			p, _ := idl.Struct(a.Name)
+			for range p.Fields {
+				owners = append(owners, a.Name)
+			}
+		}
+		for i, f := range st.AllFields() {
+			owner := ""
+			if i < len(owners) {
+				owner = owners[i]
+			}
+			sv.Fields = append(sv.Fields, s.fieldView(idl, f, owner))
+		}
+		s.Structs = append(s.Structs, sv)
+	}
+
+	for _, e := range idl.Enums() {
+		s.Enums = append(s.Enums, enumView{e.Name, e.Comment, e.Values})
+	}
+
+	var tree func(names []string, depth int) []treeNode
+	tree = func(names []string, depth int) []treeNode {
+		sort.Strings(names)
+		nodes := []treeNode{}
+		for _, n := range names {
+			node := treeNode{Type: s.typeRef(idl, n, false)}
+			if depth < len(idl.Structs()) {
+				node.Children = tree(children[n], depth+1)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	s.Tree = tree(roots, 0)
+
+	return s
+}
+
+func (s *site) fieldView(idl *barrister.Idl, f barrister.Field, inheritedFrom string) fieldView {
+	return fieldView{
+		Name:          f.Name,
+		Comment:       f.Comment,
+		Type:          s.typeRef(idl, f.Type, f.IsArray),
+		Optional:      f.Optional,
+		InheritedFrom: inheritedFrom,
+	}
+}
+
+func (s *site) typeRef(idl *barrister.Idl, name string, isArray bool) typeRef {
+	ref := typeRef{Name: name, IsArray: isArray}
+	kind := idl.TypeKind(name)
+	if kind == barrister.TypeStruct || kind == barrister.TypeEnum {
+		ref.Link = "types" + s.ext + "#" + name
+	}
+	return ref
+}
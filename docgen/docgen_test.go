@@ -0,0 +1,98 @@
+package docgen
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/coopernurse/barrister-go"
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func buildIdl(t *testing.T) *barrister.Idl {
+	idl, err := barrister.NewBuilder().
+		Comment("Animal service").
+		Enum("Kind").Value("dog").Comment("woof").Value("cat").
+		Struct("Animal").Comment("Any animal").Field("name", "string").
+		Struct("Pet").Extends("Animal").Field("kind", "Kind").
+		Field("friends", "[]Pet [optional]").Comment("other pets | if any").
+		Interface("Zoo").Comment("Zoo of pets").
+		Function("find", "Pet [optional]", "name string").Comment("returns null if not found").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idl
+}
+
+func TestGenerateHTML(t *testing.T) {
+	files, err := Generate(buildIdl(t), Options{Title: "Pets <API>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(files), 3)
+
+	index := string(files["index.html"])
+	True(t, strings.Contains(index, "<h1>Pets &lt;API&gt;</h1>"))
+	True(t, strings.Contains(index, "<pre>Animal service</pre>"))
+	True(t, strings.Contains(index, `<a href="Zoo.html">Zoo</a>`))
+	True(t, strings.Contains(index, `<a href="types.html#Pet">Pet</a>`))
+
+	zoo := string(files["Zoo.html"])
+	True(t, strings.Contains(zoo, `<h2 id="find">find</h2>`))
+	True(t, strings.Contains(zoo, `<pre>returns null if not found</pre>`))
+	True(t, strings.Contains(zoo, `Returns <a href="types.html#Pet"><code>Pet</code></a>, or null`))
+	True(t, strings.Contains(zoo, `&#34;method&#34;: &#34;Zoo.find&#34;`))
+
+	types := string(files["types.html"])
+	True(t, strings.Contains(types, "<ul>\n<li><a href=\"types.html#Animal\"><code>Animal</code></a>\n<ul>\n"+
+		"<li><a href=\"types.html#Pet\"><code>Pet</code></a></li>\n</ul>\n</li>\n</ul>"))
+	True(t, strings.Contains(types, `<p>Extended by: <a href="types.html#Pet"><code>Pet</code></a></p>`))
+	True(t, strings.Contains(types, `<td>name</td><td><code>string</code></td><td></td><td> <em>(inherited from Animal)</em></td>`))
+	True(t, strings.Contains(types, `<a href="types.html#Pet"><code>[]Pet</code></a>`))
+	True(t, strings.Contains(types, `<tr><td><code>dog</code></td><td>woof</td></tr>`))
+}
+
+func TestGenerateMarkdown(t *testing.T) {
+	files, err := Generate(buildIdl(t), Options{Format: Markdown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(files), 3)
+
+	index := string(files["index.md"])
+	True(t, strings.HasPrefix(index, "# API Documentation\n\nAnimal service\n"))
+	True(t, strings.Contains(index, "* [Zoo](Zoo.md)\n"))
+	True(t, strings.Contains(index, "* [Kind](types.md#Kind)\n"))
+
+	zoo := string(files["Zoo.md"])
+	True(t, strings.Contains(zoo, "`find(name string) Pet [optional]`"))
+	True(t, strings.Contains(zoo, "| name | `string` |  |\n"))
+	True(t, strings.Contains(zoo, "```json\n{\n  \"jsonrpc\": \"2.0\",\n  \"id\": \"1\",\n  \"method\": \"Zoo.find\",\n  \"params\": [\n    \"abc\"\n  ]\n}\n```"))
+	True(t, strings.Contains(zoo, "\"result\": {\n    \"name\": \"abc\",\n    \"kind\": \"dog\",\n    \"friends\": []\n  }"))
+
+	types := string(files["types.md"])
+	True(t, strings.Contains(types, "* [Animal](types.md#Animal)\n  * [Pet](types.md#Pet)\n"))
+	True(t, strings.Contains(types, "<a id=\"Pet\"></a>\n### Pet\n\nExtends: [Animal](types.md#Animal)\n"))
+	True(t, strings.Contains(types, "| friends | [][Pet](types.md#Pet) | yes | other pets \\| if any |\n"))
+	True(t, strings.Contains(types, "| name | `string` |  |  *(inherited from Animal)* |\n"))
+}
+
+func TestGenerateConform(t *testing.T) {
+	b, err := ioutil.ReadFile("../test/conform.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idl := barrister.MustParseIdlJson(b)
+
+	for _, format := range []string{HTML, Markdown} {
+		files, err := Generate(idl, Options{Format: format})
+		if err != nil {
+			t.Fatal(err)
+		}
+		Equals(t, len(files), 2+2)
+	}
+
+	_, err = Generate(idl, Options{Format: "pdf"})
+	Equals(t, err.Error(), "docgen: unknown format: pdf")
+}
@@ -0,0 +1,113 @@
+package docgen
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// object is a JSON object that keeps its keys in insertion order, so
+// example structs list their fields in IDL order
+type object []keyValue
+
+type keyValue struct {
+	key string
+	val interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteString("{")
+	for i, kv := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		k, err := json.Marshal(kv.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(kv.val)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteString(":")
+		b.Write(v)
+	}
+	b.WriteString("}")
+	return b.Bytes(), nil
+}
+
+// exampleValue returns an example value for f.  seen holds the structs
+// being generated, so recursive types terminate.
+func exampleValue(idl *barrister.Idl, f barrister.Field, seen map[string]bool) interface{} {
+	if f.IsArray {
+		elem := f
+		elem.IsArray = false
+		elem.Optional = false
+		if seen[f.Type] {
+			return []interface{}{}
+		}
+		return []interface{}{exampleValue(idl, elem, seen)}
+	}
+
+	switch f.Type {
+	case "string":
+		return "abc"
+	case "int":
+		return 123
+	case "float":
+		return 1.5
+	case "bool":
+		return true
+	}
+
+	if e, ok := idl.ResolveEnum(f); ok && len(e.Values) > 0 {
+		return e.Values[0].Value
+	}
+
+	s, ok := idl.ResolveStruct(f)
+	if !ok || seen[s.Name] {
+		return nil
+	}
+
+	seen[s.Name] = true
+	defer delete(seen, s.Name)
+
+	obj := object{}
+	for _, sf := range s.AllFields() {
+		obj = append(obj, keyValue{sf.Name, exampleValue(idl, sf, seen)})
+	}
+	return obj
+}
+
+// exampleJson returns v as indented JSON
+func exampleJson(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// exampleRequest returns an example JSON-RPC request and response for fn
+func exampleRequest(idl *barrister.Idl, method string, fn barrister.Function) (string, string) {
+	params := []interface{}{}
+	for _, p := range fn.Params {
+		params = append(params, exampleValue(idl, p, map[string]bool{}))
+	}
+
+	req := object{
+		{"jsonrpc", "2.0"},
+		{"id", "1"},
+		{"method", method},
+		{"params", params},
+	}
+	resp := object{
+		{"jsonrpc", "2.0"},
+		{"id", "1"},
+		{"result", exampleValue(idl, fn.Returns, map[string]bool{})},
+	}
+	return exampleJson(req), exampleJson(resp)
+}
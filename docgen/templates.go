@@ -0,0 +1,263 @@
+package docgen
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// executor is implemented by both html/template and text/template
+type executor interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+func newTemplates(format string, ext string) executor {
+	if format == Markdown {
+		funcs := template.FuncMap{
+			"type":     mdType,
+			"anchor":   func(name string) string { return `<a id="` + name + `"></a>` },
+			"cell":     mdCell,
+			"page":     func(name string) string { return name + ext },
+			"types":    func() string { return "types" + ext },
+			"typeName": typeName,
+			"flatten":  flatten,
+		}
+		return template.Must(template.New("markdown").Funcs(funcs).Parse(markdownTemplates))
+	}
+
+	funcs := htmltemplate.FuncMap{
+		"type":  htmlType,
+		"page":  func(name string) string { return name + ext },
+		"types": func() string { return "types" + ext },
+	}
+	return htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlTemplates))
+}
+
+func typeName(t typeRef) string {
+	if t.IsArray {
+		return "[]" + t.Name
+	}
+	return t.Name
+}
+
+// flatNode is a tree node with the indent needed for a nested Markdown list
+type flatNode struct {
+	Indent string
+	Node   treeNode
+}
+
+func flatten(nodes []treeNode) []flatNode {
+	var flat []flatNode
+	var walk func(nodes []treeNode, indent string)
+	walk = func(nodes []treeNode, indent string) {
+		for _, n := range nodes {
+			flat = append(flat, flatNode{indent, n})
+			walk(n.Children, indent+"  ")
+		}
+	}
+	walk(nodes, "")
+	return flat
+}
+
+func mdType(t typeRef) string {
+	if t.Link == "" {
+		return "`" + typeName(t) + "`"
+	}
+	prefix := ""
+	if t.IsArray {
+		prefix = "[]"
+	}
+	return prefix + "[" + t.Name + "](" + t.Link + ")"
+}
+
+// mdCell makes s safe to use in a Markdown table cell
+func mdCell(s string) string {
+	s = strings.Replace(s, "|", `\|`, -1)
+	return strings.Replace(strings.TrimSpace(s), "\n", "<br>", -1)
+}
+
+func htmlType(t typeRef) htmltemplate.HTML {
+	name := htmltemplate.HTMLEscapeString(typeName(t))
+	if t.Link == "" {
+		return htmltemplate.HTML("<code>" + name + "</code>")
+	}
+	link := htmltemplate.HTMLEscapeString(t.Link)
+	return htmltemplate.HTML(`<a href="` + link + `"><code>` + name + `</code></a>`)
+}
+
+const htmlTemplates = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }
+pre { background: #f4f4f4; padding: 0.5em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "tree"}}<ul>
+{{range .}}<li>{{type .Type}}{{if .Children}}
+{{template "tree" .Children}}{{end}}</li>
+{{end}}</ul>
+{{end}}
+
+{{define "index"}}{{template "header" .Title}}<h1>{{.Title}}</h1>
+{{range .Comments}}<pre>{{.}}</pre>
+{{end}}
+<h2>Interfaces</h2>
+<ul>
+{{range .Interfaces}}<li><a href="{{.File}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{if .Structs}}
+<h2>Structs</h2>
+<ul>
+{{range .Structs}}<li><a href="{{types}}#{{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}{{if .Enums}}
+<h2>Enums</h2>
+<ul>
+{{range .Enums}}<li><a href="{{types}}#{{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}{{template "footer"}}{{end}}
+
+{{define "interface"}}{{template "header" .Iface.Name}}<p><a href="{{page "index"}}">{{.Site.Title}}</a></p>
+<h1>{{.Iface.Name}}</h1>
+{{if .Iface.Comment}}<pre>{{.Iface.Comment}}</pre>
+{{end}}
+{{range .Iface.Functions}}<h2 id="{{.Name}}">{{.Name}}</h2>
+<p><code>{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}}</code> {{type $p.Type}}<code>{{end}})</code> {{type .Returns.Type}}{{if .Returns.Optional}} [optional]{{end}}</p>
+{{if .Comment}}<pre>{{.Comment}}</pre>
+{{end}}{{if .Params}}<table>
+<tr><th>Param</th><th>Type</th><th>Description</th></tr>
+{{range .Params}}<tr><td>{{.Name}}</td><td>{{type .Type}}</td><td>{{.Comment}}</td></tr>
+{{end}}</table>
+{{end}}<p>Returns {{type .Returns.Type}}{{if .Returns.Optional}}, or null{{end}}</p>
+<h3>Example request</h3>
+<pre>{{.Request}}</pre>
+<h3>Example response</h3>
+<pre>{{.Response}}</pre>
+{{end}}{{template "footer"}}{{end}}
+
+{{define "types"}}{{template "header" .Title}}<p><a href="{{page "index"}}">{{.Title}}</a></p>
+<h1>Types</h1>
+{{if .Structs}}<h2>Inheritance</h2>
+{{template "tree" .Tree}}
+<h2>Structs</h2>
+{{range .Structs}}<h3 id="{{.Name}}">{{.Name}}</h3>
+{{if .Ancestors}}<p>Extends: {{range $i, $a := .Ancestors}}{{if $i}} &gt; {{end}}{{type $a}}{{end}}</p>
+{{end}}{{if .Subtypes}}<p>Extended by: {{range $i, $s := .Subtypes}}{{if $i}}, {{end}}{{type $s}}{{end}}</p>
+{{end}}{{if .Comment}}<pre>{{.Comment}}</pre>
+{{end}}<table>
+<tr><th>Field</th><th>Type</th><th>Optional</th><th>Description</th></tr>
+{{range .Fields}}<tr><td>{{.Name}}</td><td>{{type .Type}}</td><td>{{if .Optional}}yes{{end}}</td><td>{{.Comment}}{{if .InheritedFrom}} <em>(inherited from {{.InheritedFrom}})</em>{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{if .Enums}}<h2>Enums</h2>
+{{range .Enums}}<h3 id="{{.Name}}">{{.Name}}</h3>
+{{if .Comment}}<pre>{{.Comment}}</pre>
+{{end}}<table>
+<tr><th>Value</th><th>Description</th></tr>
+{{range .Values}}<tr><td><code>{{.Value}}</code></td><td>{{.Comment}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}{{template "footer"}}{{end}}
+`
+
+const markdownTemplates = `
+{{define "tree"}}{{range .}}{{template "treeNode" .}}{{end}}{{end}}
+
+{{define "treeNode"}}{{.Indent}}* {{type .Node.Type}}
+{{end}}
+
+{{define "index"}}# {{.Title}}
+{{range .Comments}}
+{{.}}
+{{end}}
+## Interfaces
+
+{{range .Interfaces}}* [{{.Name}}]({{.File}})
+{{end}}{{if .Structs}}
+## Structs
+
+{{range .Structs}}* [{{.Name}}]({{types}}#{{.Name}})
+{{end}}{{end}}{{if .Enums}}
+## Enums
+
+{{range .Enums}}* [{{.Name}}]({{types}}#{{.Name}})
+{{end}}{{end}}{{end}}
+
+{{define "interface"}}[{{.Site.Title}}]({{page "index"}})
+
+# {{.Iface.Name}}
+{{if .Iface.Comment}}
+{{.Iface.Comment}}
+{{end}}{{range .Iface.Functions}}
+{{anchor .Name}}
+## {{.Name}}
+
+` + "`" + `{{.Name}}({{range $i, $p := .Params}}{{if $i}}, {{end}}{{$p.Name}} {{typeName $p.Type}}{{end}}) {{typeName .Returns.Type}}{{if .Returns.Optional}} [optional]{{end}}` + "`" + `
+{{if .Comment}}
+{{.Comment}}
+{{end}}{{if .Params}}
+| Param | Type | Description |
+|-------|------|-------------|
+{{range .Params}}| {{.Name}} | {{type .Type}} | {{cell .Comment}} |
+{{end}}{{end}}
+Returns {{type .Returns.Type}}{{if .Returns.Optional}}, or null{{end}}
+
+### Example request
+
+` + "```json" + `
+{{.Request}}
+` + "```" + `
+
+### Example response
+
+` + "```json" + `
+{{.Response}}
+` + "```" + `
+{{end}}{{end}}
+
+{{define "types"}}[{{.Title}}]({{page "index"}})
+
+# Types
+{{if .Structs}}
+## Inheritance
+
+{{template "tree" (flatten .Tree)}}
+## Structs
+{{range .Structs}}
+{{anchor .Name}}
+### {{.Name}}
+{{if .Ancestors}}
+Extends: {{range $i, $a := .Ancestors}}{{if $i}} > {{end}}{{type $a}}{{end}}
+{{end}}{{if .Subtypes}}
+Extended by: {{range $i, $s := .Subtypes}}{{if $i}}, {{end}}{{type $s}}{{end}}
+{{end}}{{if .Comment}}
+{{.Comment}}
+{{end}}
+| Field | Type | Optional | Description |
+|-------|------|----------|-------------|
+{{range .Fields}}| {{.Name}} | {{type .Type}} | {{if .Optional}}yes{{end}} | {{cell .Comment}}{{if .InheritedFrom}} *(inherited from {{.InheritedFrom}})*{{end}} |
+{{end}}{{end}}{{end}}{{if .Enums}}
+## Enums
+{{range .Enums}}
+{{anchor .Name}}
+### {{.Name}}
+{{if .Comment}}
+{{.Comment}}
+{{end}}
+| Value | Description |
+|-------|-------------|
+{{range .Values}}| ` + "`" + `{{.Value}}` + "`" + ` | {{cell .Comment}} |
+{{end}}{{end}}{{end}}{{end}}
+`
@@ -0,0 +1,177 @@
+package barrister
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structFields maps the fields of an IDL struct to fields of a Go struct.
+// Field resolution is relatively expensive, so results are cached per
+// Go type and IDL struct.
+type structFields struct {
+	// index of the Go field for each IDL field, in the same
+	// order as Struct.allFields
+	index [][]int
+
+	// non-nil if the Go struct can't represent the IDL struct
+	err error
+}
+
+type structFieldsKey struct {
+	goType    reflect.Type
+	idlStruct string
+	idlFields string
+}
+
+var structFieldsCache sync.Map
+
+// goField is a candidate Go struct field for an IDL field
+type goField struct {
+	name  string
+	tag   string
+	index []int
+	depth int
+}
+
+// resolveStructFields returns the Go field for each field of s in t.
+//
+// Fields are resolved in this order:
+//
+// 1) A field whose `json` tag name matches the IDL field name
+//
+// 2) A field whose name matches the IDL field name
+//
+// 3) A field whose name matches the IDL field name with the first letter capitalized
+//
+// 4) A field whose name matches the IDL field name converted to MixedCaps
+//
+// 5) A field whose name matches the IDL field name ignoring case and underscores
+//
+// Fields of embedded structs are considered, and shallower fields win.  If
+// more than one field matches at the same depth an error is returned.
+func resolveStructFields(t reflect.Type, s *Struct) *structFields {
+	key := structFieldsKey{t, s.Name, s.fieldKey}
+	cached, ok := structFieldsCache.Load(key)
+	if ok {
+		return cached.(*structFields)
+	}
+
+	candidates := collectGoFields(t, nil, 0, map[reflect.Type]bool{})
+
+	// fields explicitly tagged with the name of an IDL field are not
+	// considered by the name heuristics for other IDL fields
+	claimed := map[string]bool{}
+	for _, f := range s.allFields {
+		claimed[f.Name] = true
+	}
+
+	sf := &structFields{index: make([][]int, len(s.allFields))}
+	for x, f := range s.allFields {
+		matchers := []func(g goField) bool{
+			func(g goField) bool { return g.tag == f.Name },
+			func(g goField) bool { return g.name == f.Name },
+			func(g goField) bool { return g.name == capitalize(f.Name) },
+			func(g goField) bool { return g.name == MixedCapsNaming(f.Name) },
+			func(g goField) bool { return normalizeName(g.name) == normalizeName(f.Name) },
+		}
+
+		for m, matcher := range matchers {
+			var found []goField
+			for _, g := range candidates {
+				if m > 0 && g.tag != "" && g.tag != f.Name && claimed[g.tag] {
+					continue
+				}
+				if matcher(g) && (len(found) == 0 || g.depth <= found[0].depth) {
+					if len(found) > 0 && g.depth < found[0].depth {
+						found = nil
+					}
+					found = append(found, g)
+				}
+			}
+
+			if len(found) > 1 {
+				names := make([]string, len(found))
+				for i, g := range found {
+					names[i] = g.name
+				}
+				sf.err = fmt.Errorf("Struct: %v has ambiguous fields for IDL field: %s.%s (%s)",
+					t, s.Name, f.Name, strings.Join(names, ", "))
+				break
+			} else if len(found) == 1 {
+				sf.index[x] = found[0].index
+				break
+			}
+		}
+
+		if sf.err != nil {
+			break
+		} else if sf.index[x] == nil {
+			sf.err = fmt.Errorf("Struct: %v is missing required field: %s",
+				t, capitalize(f.Name))
+			break
+		}
+	}
+
+	structFieldsCache.Store(key, sf)
+	return sf
+}
+
+// collectGoFields returns all settable fields of t, including fields
+// of embedded structs
+func collectGoFields(t reflect.Type, index []int, depth int, seen map[reflect.Type]bool) []goField {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	fields := []goField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, skip := jsonFieldName(f)
+		if skip {
+			continue
+		}
+
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(index)] = i
+
+		if f.PkgPath == "" {
+			fields = append(fields, goField{f.Name, tag, idx, depth})
+		}
+
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr && f.PkgPath == "" {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, collectGoFields(ft, idx, depth+1, seen)...)
+			}
+		}
+	}
+	return fields
+}
+
+// fieldByIndex returns the nested field of v at index, allocating
+// any nil embedded struct pointers along the way
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for x, i := range index {
+		if x > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// normalizeName lower cases s and removes underscores
+func normalizeName(s string) string {
+	return strings.ToLower(strings.Replace(s, "_", "", -1))
+}
@@ -39,11 +39,38 @@ type generateGo struct {
 	// if false, "omitempty" will be added to the json tag
 	optionalToPtr bool
 
+	// if true, [optional] fields and results will be generated as
+	// barrister.Optional, with "omitzero" in the json tag.  Takes
+	// precedence over optionalToPtr.
+	genericOptionals bool
+
 	// imports to add
 	imports []string
 
 	// base import string to prefix to imports values
 	baseImport string
+
+	// imports required by custom type mappings used in this package
+	typeImports []string
+
+	// standard library imports required by the generated code
+	stdImports []string
+
+	// if true, generated enum types accept values that aren't in the IDL
+	allowUnknownEnums bool
+
+	// if true, a Dispatcher is generated for each interface
+	dispatch bool
+
+	// if true, a mock and fake client constructor are generated for each
+	// interface
+	mocks bool
+
+	// if true, the generated code uses the barrister package
+	importBarrister bool
+
+	// converts IDL names to Go identifiers
+	naming NamingStrategy
 }
 
 func (g *generateGo) hasInterface() bool {
@@ -51,13 +78,22 @@ func (g *generateGo) hasInterface() bool {
 }
 
 func (g *generateGo) generate() []byte {
+	// body is generated first so that imports required by
+	// type mappings are known when the header is written
+	body := g.generateBody()
+
 	b := &bytes.Buffer{}
-	line(b, 0, fmt.Sprintf("// Code generated by idl2go from JSON generated by Barrister v%s", g.idl.Meta.BarristerVersion))
+	line(b, 0, fmt.Sprintf("// Code generated by idl2go from IDL generated by Barrister v%s. DO NOT EDIT.\n", g.idl.Meta.BarristerVersion))
 	line(b, 0, fmt.Sprintf("package %s\n", g.pkgName))
 	line(b, 0, "import (")
-	if g.hasInterface() {
-		line(b, 1, `"fmt"`)
-		line(b, 1, `"reflect"`)
+	sort.Strings(g.stdImports)
+	for _, imp := range g.stdImports {
+		line(b, 1, fmt.Sprintf("\"%s\"", imp))
+	}
+	for _, imp := range g.typeImports {
+		line(b, 1, fmt.Sprintf("\"%s\"", imp))
+	}
+	if g.hasInterface() || g.importBarrister {
 		line(b, 1, `"github.com/coopernurse/barrister-go"`)
 	}
 	for _, imp := range g.imports {
@@ -65,6 +101,12 @@ func (g *generateGo) generate() []byte {
 	}
 	line(b, 0, ")\n")
 
+	b.Write(body)
+	return b.Bytes()
+}
+
+func (g *generateGo) generateBody() []byte {
+	b := &bytes.Buffer{}
 	if g.hasInterface() {
 		line(b, 0, "const BarristerVersion string = \""+g.idl.Meta.BarristerVersion+"\"")
 		line(b, 0, "const BarristerChecksum string = \""+g.idl.Meta.Checksum+"\"")
@@ -72,8 +114,10 @@ func (g *generateGo) generate() []byte {
 		line(b, 0, "")
 	}
 
-	for name, _ := range g.pkgIdl.enums {
-		g.generateEnum(b, name)
+	for _, elem := range g.pkgIdl.elems {
+		if elem.Type == "enum" {
+			g.generateEnum(b, elem.Name)
+		}
 	}
 
 	for _, elem := range g.pkgIdl.elems {
@@ -83,6 +127,8 @@ func (g *generateGo) generate() []byte {
 				panic("No struct found: " + elem.Name)
 			}
 			g.generateStruct(b, s)
+			g.generateValidate(b, s)
+			g.generateUnmarshalIdl(b, s)
 		}
 	}
 	line(b, 0, "")
@@ -92,6 +138,13 @@ func (g *generateGo) generate() []byte {
 			g.generateInterface(b, name)
 			line(b, 0, "}\n")
 			g.generateProxy(b, name)
+			g.generateBatch(b, name)
+			if g.dispatch {
+				g.generateDispatcher(b, name)
+			}
+			if g.mocks {
+				g.generateMock(b, name)
+			}
 		}
 
 		g.generateNewServer(b)
@@ -108,7 +161,21 @@ func (g *generateGo) generateIdlJson(b *bytes.Buffer) {
 	}
 	idlstr := strings.Replace(string(idlbytes), "`", "`+\"`\"+`", -1)
 	line(b, 0, "")
-	line(b, 0, "var IdlJsonRaw = `"+idlstr+"`")
+	line(b, 0, "var IdlJsonRaw = `"+idlstr+"`\n")
+
+	g.addStdImport("sync")
+	line(b, 0, "var (")
+	line(b, 1, "_idl     *barrister.Idl")
+	line(b, 1, "_idlOnce sync.Once")
+	line(b, 0, ")\n")
+	line(b, 0, "// BarristerIdl returns IdlJsonRaw parsed.  It is parsed on first use and")
+	line(b, 0, "// shared by all callers.")
+	line(b, 0, "func BarristerIdl() *barrister.Idl {")
+	line(b, 1, "_idlOnce.Do(func() {")
+	line(b, 2, "_idl = barrister.MustParseIdlJson([]byte(IdlJsonRaw))")
+	line(b, 1, "})")
+	line(b, 1, "return _idl")
+	line(b, 0, "}")
 }
 
 func (g *generateGo) generateEnum(b *bytes.Buffer, enumName string) {
@@ -117,58 +184,494 @@ func (g *generateGo) generateEnum(b *bytes.Buffer, enumName string) {
 		panic("No enum found: " + enumName)
 	}
 
-	goName := capitalizeAndStripMatchingPkg(enumName, g.pkgName)
+	goName := g.goName(enumName)
+	if e, ok := g.idl.Enum(enumName); ok {
+		comment(b, 0, e.Comment)
+	}
 	line(b, 0, fmt.Sprintf("type %s string", goName))
 	line(b, 0, "const (")
+	consts := make([]string, len(vals))
 	for x, val := range vals {
-		typeStr := ""
-		if x == 0 {
-			typeStr = goName
-		}
-		line(b, 1, fmt.Sprintf("%s%s %s = \"%s\"",
-			goName, capitalize(val.Value), typeStr, val.Value))
+		consts[x] = goName + g.naming(val.Value)
+		comment(b, 1, val.Comment)
+		line(b, 1, fmt.Sprintf("%s %s = \"%s\"", consts[x], goName, val.Value))
 	}
 	line(b, 0, ")\n")
+
+	line(b, 0, fmt.Sprintf("// Values returns all values of %s, in IDL order", goName))
+	line(b, 0, fmt.Sprintf("func (%s) Values() []%s {", goName, goName))
+	line(b, 1, fmt.Sprintf("return []%s{%s}", goName, strings.Join(consts, ", ")))
+	line(b, 0, "}\n")
+
+	line(b, 0, fmt.Sprintf("// IsValid returns true if e is one of the values of %s", goName))
+	line(b, 0, fmt.Sprintf("func (e %s) IsValid() bool {", goName))
+	line(b, 1, "switch e {")
+	line(b, 1, fmt.Sprintf("case %s:", strings.Join(consts, ", ")))
+	line(b, 2, "return true")
+	line(b, 1, "}")
+	line(b, 1, "return false")
+	line(b, 0, "}\n")
+
+	line(b, 0, fmt.Sprintf("func (e %s) String() string {", goName))
+	line(b, 1, "return string(e)")
+	line(b, 0, "}\n")
+
+	g.importBarrister = true
+	line(b, 0, "// UnmarshalIdl implements barrister.IdlUnmarshaler")
+	line(b, 0, fmt.Sprintf("func (e *%s) UnmarshalIdl(v interface{}) error {", goName))
+	line(b, 1, fmt.Sprintf("if x, ok := v.(%s); ok {", goName))
+	line(b, 2, "v = string(x)")
+	line(b, 1, "}")
+	if g.allowUnknownEnums {
+		line(b, 1, "s, err := barrister.DecodeString(v)")
+	} else {
+		line(b, 1, fmt.Sprintf("s, err := barrister.DecodeEnum(v, _%sValues)", goName))
+	}
+	line(b, 1, "if err != nil {")
+	line(b, 2, "return err")
+	line(b, 1, "}")
+	line(b, 1, fmt.Sprintf("*e = %s(s)", goName))
+	line(b, 1, "return nil")
+	line(b, 0, "}\n")
+
+	if g.allowUnknownEnums {
+		return
+	}
+
+	quoted := make([]string, len(vals))
+	for x, val := range vals {
+		quoted[x] = fmt.Sprintf("%q", val.Value)
+	}
+	line(b, 0, fmt.Sprintf("var _%sValues = []string{%s}\n", goName, strings.Join(quoted, ", ")))
+
+	g.addStdImport("encoding/json")
+	g.addStdImport("fmt")
+
+	line(b, 0, fmt.Sprintf("// MarshalJSON returns an error if e is not one of the values of %s", goName))
+	line(b, 0, fmt.Sprintf("func (e %s) MarshalJSON() ([]byte, error) {", goName))
+	line(b, 1, "if !e.IsValid() {")
+	line(b, 2, fmt.Sprintf("return nil, fmt.Errorf(\"invalid %s value: %%q\", string(e))", enumName))
+	line(b, 1, "}")
+	line(b, 1, "return json.Marshal(string(e))")
+	line(b, 0, "}\n")
+
+	line(b, 0, fmt.Sprintf("// UnmarshalJSON returns an error if data is not one of the values of %s", goName))
+	line(b, 0, fmt.Sprintf("func (e *%s) UnmarshalJSON(data []byte) error {", goName))
+	line(b, 1, "var s string")
+	line(b, 1, "if err := json.Unmarshal(data, &s); err != nil {")
+	line(b, 2, "return err")
+	line(b, 1, "}")
+	line(b, 1, fmt.Sprintf("if !%s(s).IsValid() {", goName))
+	line(b, 2, fmt.Sprintf("return fmt.Errorf(\"invalid %s value: %%q\", s)", enumName))
+	line(b, 1, "}")
+	line(b, 1, fmt.Sprintf("*e = %s(s)", goName))
+	line(b, 1, "return nil")
+	line(b, 0, "}\n")
+}
+
+// generateUnmarshalIdl writes an UnmarshalIdl method for the struct.  It is
+// not generated for structs that use custom type mappings, which are decoded
+// with barrister.Convert.
+func (g *generateGo) generateUnmarshalIdl(b *bytes.Buffer, s *Struct) {
+	if g.usesMapping("", Field{Type: s.Name}, map[string]bool{}) {
+		return
+	}
+	g.importBarrister = true
+
+	goName := g.goName(s.Name)
+	line(b, 0, "// UnmarshalIdl implements barrister.IdlUnmarshaler")
+	line(b, 0, fmt.Sprintf("func (s *%s) UnmarshalIdl(v interface{}) error {", goName))
+	line(b, 1, fmt.Sprintf("if x, ok := v.(%s); ok {", goName))
+	line(b, 2, "*s = x")
+	line(b, 2, "return nil")
+	line(b, 1, "}")
+	line(b, 1, "m, err := barrister.DecodeObject(v)")
+	line(b, 1, "if err != nil {")
+	line(b, 2, "return err")
+	line(b, 1, "}")
+	if s.Extends != "" {
+		_, parent := splitNs(g.goName(s.Extends))
+		line(b, 1, fmt.Sprintf("if err := s.%s.UnmarshalIdl(m); err != nil {", parent))
+		line(b, 2, "return err")
+		line(b, 1, "}")
+	}
+	for _, f := range s.Fields {
+		line(b, 1, fmt.Sprintf("if _v, ok, err := barrister.FieldValue(m, %q, %v); err != nil {", f.Name, f.Optional))
+		line(b, 2, "return err")
+		line(b, 1, "} else if ok {")
+		name := f.Name
+		g.generateDecode(b, 2, "s."+g.naming(f.Name), f, "_v", func(err string) string {
+			return fmt.Sprintf("return barrister.FieldError(%s, %q)", err, name)
+		})
+		if f.Optional && g.genericOptionals {
+			// FieldValue doesn't distinguish null from absent
+			line(b, 1, fmt.Sprintf("} else if _, null := m[%q]; null {", f.Name))
+			line(b, 2, fmt.Sprintf("s.%s = %s{Null: true}", g.naming(f.Name), g.goType(s.Name+"."+f.Name, f)))
+		}
+		line(b, 1, "}")
+	}
+	line(b, 1, "return nil")
+	line(b, 0, "}\n")
+}
+
+// generateDecode writes code that decodes val, a value decoded by a
+// Serializer, into target, which has the Go type of f.  fail returns the
+// statement that returns the error named by its argument.  f must not use
+// custom type mappings.
+func (g *generateGo) generateDecode(b *bytes.Buffer, level int, target string, f Field, val string, fail func(err string) string) {
+	if f.Optional && g.genericOptionals {
+		inner := f
+		inner.Optional = false
+		line(b, level, fmt.Sprintf("var _o %s", g.goType("", inner)))
+		g.generateDecode(b, level, "_o", inner, val, fail)
+		line(b, level, fmt.Sprintf("%s = barrister.Some(_o)", target))
+		return
+	}
+	ptr := f.Optional && g.optionalToPtr
+
+	if f.IsArray {
+		elem := Field{Type: f.Type}
+		line(b, level, fmt.Sprintf("if _a, _err := barrister.DecodeArray(%s); _err != nil {", val))
+		line(b, level+1, fail("_err"))
+		line(b, level, "} else {")
+		line(b, level+1, fmt.Sprintf("_s := make(%s, len(_a))", g.goType("", Field{Type: f.Type, IsArray: true})))
+		line(b, level+1, "for _i, _e := range _a {")
+		g.generateDecode(b, level+2, "_s[_i]", elem, "_e", func(err string) string {
+			return fail(fmt.Sprintf("barrister.ElemError(%s, _i)", err))
+		})
+		line(b, level+1, "}")
+		if ptr {
+			line(b, level+1, fmt.Sprintf("%s = &_s", target))
+		} else {
+			line(b, level+1, fmt.Sprintf("%s = _s", target))
+		}
+		line(b, level, "}")
+		return
+	}
+
+	decodeFunc := ""
+	switch f.Type {
+	case "string":
+		decodeFunc = "DecodeString"
+	case "int":
+		decodeFunc = "DecodeInt"
+	case "float":
+		decodeFunc = "DecodeFloat"
+	case "bool":
+		decodeFunc = "DecodeBool"
+	}
+
+	if decodeFunc != "" {
+		line(b, level, fmt.Sprintf("if _x, _err := barrister.%s(%s); _err != nil {", decodeFunc, val))
+		line(b, level+1, fail("_err"))
+		line(b, level, "} else {")
+		if ptr {
+			line(b, level+1, fmt.Sprintf("%s = &_x", target))
+		} else {
+			line(b, level+1, fmt.Sprintf("%s = _x", target))
+		}
+		line(b, level, "}")
+		return
+	}
+
+	// optional structs are always pointers
+	_, isStruct := g.idl.structs[f.Type]
+	if ptr || (f.Optional && isStruct) {
+		line(b, level, fmt.Sprintf("%s = new(%s)", target, g.goType("", Field{Type: f.Type})))
+	}
+	line(b, level, fmt.Sprintf("if _err := %s.UnmarshalIdl(%s); _err != nil {", target, val))
+	line(b, level+1, fail("_err"))
+	line(b, level, "}")
+}
+
+// generateDecodeValue is like generateDecode, but first checks whether val
+// already has the Go type of the array f, as it does for in-process calls.
+// Other types check this in their Decode func or UnmarshalIdl method.
+func (g *generateGo) generateDecodeValue(b *bytes.Buffer, level int, target string, goType string, f Field, val string, fail func(err string) string) {
+	if !f.IsArray && !(f.Optional && g.genericOptionals) {
+		g.generateDecode(b, level, target, f, val, fail)
+		return
+	}
+	line(b, level, fmt.Sprintf("if _x, _ok := %s.(%s); _ok {", val, goType))
+	line(b, level+1, fmt.Sprintf("%s = _x", target))
+	line(b, level, "} else {")
+	g.generateDecode(b, level+1, target, f, val, fail)
+	line(b, level, "}")
+}
+
+// generateDispatcher writes a barrister.Dispatcher for the interface
+func (g *generateGo) generateDispatcher(b *bytes.Buffer, ifaceName string) {
+	funcs := g.idl.interfaces[ifaceName]
+	goIfaceName := g.naming(ifaceName)
+	goName := goIfaceName + "Dispatcher"
+
+	line(b, 0, fmt.Sprintf("// %s calls the methods of a %s handler without reflection.", goName, goIfaceName))
+	line(b, 0, "// It is registered with barrister.Server.AddDispatcher by NewServer.")
+	line(b, 0, fmt.Sprintf("type %s struct{}\n", goName))
+
+	line(b, 0, "// Dispatch implements barrister.Dispatcher")
+	line(b, 0, fmt.Sprintf("func (%s) Dispatch(idl *barrister.Idl, handler interface{}, function string, params []interface{}) (interface{}, error) {", goName))
+	line(b, 1, fmt.Sprintf("h, ok := handler.(%s)", goIfaceName))
+	line(b, 1, "if !ok {")
+	line(b, 2, fmt.Sprintf("return nil, barrister.HandlerError(%q, handler)", ifaceName))
+	line(b, 1, "}")
+	line(b, 1, "switch function {")
+	for _, fn := range funcs {
+		method := ifaceName + "." + fn.Name
+		line(b, 1, fmt.Sprintf("case %q:", fn.Name))
+		args := make([]string, len(fn.Params))
+		for x, p := range fn.Params {
+			key := method + "." + p.Name
+			goType := g.goType(key, p)
+			args[x] = fmt.Sprintf("_p%d", x)
+			param := fmt.Sprintf("params[%d]", x)
+			line(b, 2, fmt.Sprintf("var %s %s", args[x], goType))
+
+			if g.usesMapping(key, p, map[string]bool{}) {
+				g.addStdImport("reflect")
+				line(b, 2, fmt.Sprintf("if _x, _err := barrister.Convert(idl, &idl.Method(%q).Params[%d], reflect.TypeOf(%s), %s, \"\"); _err != nil {",
+					method, x, args[x], param))
+				line(b, 3, fmt.Sprintf("return nil, barrister.ParamError(_err, %d)", x))
+				line(b, 2, "} else {")
+				line(b, 3, fmt.Sprintf("%s = _x.(%s)", args[x], goType))
+				line(b, 2, "}")
+				continue
+			}
+
+			n := x
+			g.generateDecodeValue(b, 2, args[x], goType, p, param, func(err string) string {
+				return fmt.Sprintf("return nil, barrister.ParamError(%s, %d)", err, n)
+			})
+		}
+		line(b, 2, fmt.Sprintf("return h.%s(%s)", g.naming(fn.Name), strings.Join(args, ", ")))
+	}
+	line(b, 1, "}")
+	line(b, 1, fmt.Sprintf("return nil, &barrister.JsonRpcError{Code: -32601, Message: \"Unsupported method: %s.\" + function}", ifaceName))
+	line(b, 0, "}\n")
+}
+
+// generateMock writes a mock implementation of the interface, and a
+// function that returns a Client that calls an implementation in-process
+func (g *generateGo) generateMock(b *bytes.Buffer, ifaceName string) {
+	funcs := g.idl.interfaces[ifaceName]
+	goIfaceName := g.naming(ifaceName)
+	goName := goIfaceName + "Mock"
+
+	line(b, 0, fmt.Sprintf("// %s is a %s for tests.  Each method records the call and calls the", goName, goIfaceName))
+	line(b, 0, "// matching Func field, or returns a -32601 error if the field is nil.")
+	line(b, 0, fmt.Sprintf("type %s struct {", goName))
+	line(b, 1, "barrister.MockRecorder\n")
+	for _, fn := range funcs {
+		params, _ := g.mockParams(ifaceName, fn)
+		retType := g.goType(ifaceName+"."+fn.Name, fn.Returns)
+		line(b, 1, fmt.Sprintf("%sFunc func(%s) (%s, error)", g.naming(fn.Name), params, retType))
+	}
+	line(b, 0, "}\n")
+
+	for _, fn := range funcs {
+		method := ifaceName + "." + fn.Name
+		fnName := g.naming(fn.Name)
+		params, idents := g.mockParams(ifaceName, fn)
+		args := strings.Join(idents, ", ")
+		zeroVal := g.zeroVal(method, fn.Returns)
+
+		line(b, 0, fmt.Sprintf("func (_m *%s) %s(%s) (%s, error) {", goName, fnName, params, g.goType(method, fn.Returns)))
+		line(b, 1, fmt.Sprintf("_m.MockRecorder.Record(%s)", strings.Join(append([]string{fmt.Sprintf("%q", method)}, idents...), ", ")))
+		line(b, 1, fmt.Sprintf("if _m.%sFunc == nil {", fnName))
+		line(b, 2, fmt.Sprintf("return %s, &barrister.JsonRpcError{Code: -32601, Message: \"%s: %sFunc not set\"}", zeroVal, method, fnName))
+		line(b, 1, "}")
+		line(b, 1, fmt.Sprintf("return _m.%sFunc(%s)", fnName, args))
+		line(b, 0, "}\n")
+	}
+
+	lower := escReserved(strings.ToLower(ifaceName))
+	line(b, 0, fmt.Sprintf("// New%sFakeClient returns a Client that calls %s in-process.  Requests", goIfaceName, lower))
+	line(b, 0, "// and responses are encoded as they are by a remote Server, so a proxy using")
+	line(b, 0, "// the Client behaves as it would over HTTP.")
+	line(b, 0, fmt.Sprintf("func New%sFakeClient(%s %s) barrister.Client {", goIfaceName, lower, goIfaceName))
+	line(b, 1, "_svr := barrister.NewJSONServer(BarristerIdl(), false)")
+	if g.dispatch {
+		line(b, 1, fmt.Sprintf("_svr.AddDispatcher(%q, %s, %sDispatcher{})", ifaceName, lower, goIfaceName))
+	} else {
+		line(b, 1, fmt.Sprintf("_svr.AddHandler(%q, %s)", ifaceName, lower))
+	}
+	line(b, 1, "return barrister.NewServerClient(&_svr)")
+	line(b, 0, "}\n")
+}
+
+// mockParams returns the Go param list of the function and the param
+// identifiers
+func (g *generateGo) mockParams(ifaceName string, fn Function) (string, []string) {
+	params := []string{}
+	idents := []string{}
+	for _, p := range fn.Params {
+		ident := escReserved(p.Name)
+		params = append(params, fmt.Sprintf("%s %s", ident, g.goType(ifaceName+"."+fn.Name+"."+p.Name, p)))
+		idents = append(idents, ident)
+	}
+	return strings.Join(params, ", "), idents
+}
+
+// addStdImport adds a standard library import to the generated file
+func (g *generateGo) addStdImport(imp string) {
+	if !stringInSlice(imp, g.stdImports) {
+		g.stdImports = append(g.stdImports, imp)
+	}
 }
 
 func (g *generateGo) generateStruct(b *bytes.Buffer, s *Struct) {
-	goName := capitalizeAndStripMatchingPkg(s.Name, g.pkgName)
+	goName := g.goName(s.Name)
+	comment(b, 0, s.Comment)
 	line(b, 0, fmt.Sprintf("type %s struct {", goName))
 	if s.Extends != "" {
-		line(b, 1, capitalizeAndStripMatchingPkg(s.Extends, g.pkgName))
+		line(b, 1, g.goName(s.Extends))
 	}
 	for _, f := range s.Fields {
-		goName = capitalize(f.Name)
+		goName = g.naming(f.Name)
 		omit := ""
-		if f.Optional {
+		if f.Optional && g.genericOptionals {
+			omit = ",omitzero"
+		} else if f.Optional {
 			omit = ",omitempty"
 		}
+		comment(b, 1, f.Comment)
 		line(b, 1, fmt.Sprintf("%s\t%s\t`json:\"%s%s\"`",
-			goName, f.goType(g.idl, g.optionalToPtr, g.pkgName), f.Name, omit))
+			goName, g.goType(s.Name+"."+f.Name, f), f.Name, omit))
+	}
+	line(b, 0, "}\n")
+}
+
+// generateValidate writes a Validate method for the struct that checks the
+// constraints encoding/json can't: required arrays are not nil, and enum
+// values are in the IDL.  Nested structs, array elements and the parent
+// struct are validated too.
+func (g *generateGo) generateValidate(b *bytes.Buffer, s *Struct) {
+	goName := g.goName(s.Name)
+	line(b, 0, "// Validate returns an error if a required field is missing or an enum")
+	line(b, 0, "// field has a value that isn't in the IDL")
+	line(b, 0, fmt.Sprintf("func (s %s) Validate() error {", goName))
+	if s.Extends != "" {
+		_, parent := splitNs(g.goName(s.Extends))
+		line(b, 1, fmt.Sprintf("if err := s.%s.Validate(); err != nil {", parent))
+		line(b, 2, "return err")
+		line(b, 1, "}")
+	}
+	for _, f := range s.Fields {
+		ident := "s." + g.naming(f.Name)
+		if f.IsArray && !f.Optional {
+			g.addStdImport("fmt")
+			line(b, 1, fmt.Sprintf("if %s == nil {", ident))
+			line(b, 2, fmt.Sprintf("return fmt.Errorf(\"%s: required value is missing\")", f.Name))
+			line(b, 1, "}")
+		}
+		g.generateValidateField(b, 1, ident, s.Name+"."+f.Name, f)
 	}
+	line(b, 1, "return nil")
 	line(b, 0, "}\n")
 }
 
+// generateValidateField writes the checks of the enum values and nested
+// structs in the struct field f, whose value is ident
+func (g *generateGo) generateValidateField(b *bytes.Buffer, level int, ident string, key string, f Field) {
+	ptr := f.Optional && g.optionalToPtr
+
+	if g.typeMapping(key, f) != nil {
+		return
+	}
+
+	_, isStruct := g.idl.structs[f.Type]
+	_, isEnum := g.idl.enums[f.Type]
+	if !isStruct && !isEnum {
+		return
+	}
+	g.addStdImport("fmt")
+
+	if f.Optional && g.genericOptionals {
+		inner := f
+		inner.Optional = false
+		line(b, level, fmt.Sprintf("if %s.Set {", ident))
+		g.generateValidateField(b, level+1, ident+".Value", key, inner)
+		line(b, level, "}")
+		return
+	}
+
+	if f.IsArray {
+		lvl := level
+		if ptr {
+			line(b, level, fmt.Sprintf("if %s != nil {", ident))
+			ident = "*" + ident
+			lvl++
+		}
+		line(b, lvl, fmt.Sprintf("for i, v := range %s {", ident))
+		if isStruct {
+			line(b, lvl+1, "if err := v.Validate(); err != nil {")
+			line(b, lvl+2, fmt.Sprintf("return fmt.Errorf(\"%s[%%d].%%s\", i, err)", f.Name))
+		} else {
+			line(b, lvl+1, "if !v.IsValid() {")
+			line(b, lvl+2, fmt.Sprintf("return fmt.Errorf(\"%s[%%d]: invalid value: %%q\", i, v)", f.Name))
+		}
+		line(b, lvl+1, "}")
+		line(b, lvl, "}")
+		if ptr {
+			line(b, level, "}")
+		}
+		return
+	}
+
+	if isStruct {
+		// optional structs are always pointers
+		if f.Optional {
+			line(b, level, fmt.Sprintf("if %s != nil {", ident))
+			line(b, level+1, fmt.Sprintf("if err := %s.Validate(); err != nil {", ident))
+			line(b, level+2, fmt.Sprintf("return fmt.Errorf(\"%s.%%s\", err)", f.Name))
+			line(b, level+1, "}")
+			line(b, level, "}")
+		} else {
+			line(b, level, fmt.Sprintf("if err := %s.Validate(); err != nil {", ident))
+			line(b, level+1, fmt.Sprintf("return fmt.Errorf(\"%s.%%s\", err)", f.Name))
+			line(b, level, "}")
+		}
+		return
+	}
+
+	cond := fmt.Sprintf("!%s.IsValid()", ident)
+	val := ident
+	if ptr {
+		cond = fmt.Sprintf("%s != nil && !%s.IsValid()", ident, ident)
+		val = "*" + ident
+	} else if f.Optional {
+		// omitempty: the zero value means the field is absent
+		cond = fmt.Sprintf("%s != \"\" && %s", ident, cond)
+	}
+	line(b, level, fmt.Sprintf("if %s {", cond))
+	line(b, level+1, fmt.Sprintf("return fmt.Errorf(\"%s: invalid value: %%q\", %s)", f.Name, val))
+	line(b, level, "}")
+}
+
 func (g *generateGo) generateNewServer(b *bytes.Buffer) {
 	ifaceKeys := sortedKeys(g.idl.interfaces)
 	ifaces := ""
 	ifaceIdents := ""
 	for _, name := range ifaceKeys {
-		upper := capitalize(name)
+		upper := g.naming(name)
 		lower := escReserved(strings.ToLower(name))
 		ifaces = fmt.Sprintf("%s, %s %s", ifaces, lower, upper)
 		ifaceIdents += ", " + lower
 	}
 
 	line(b, 0, fmt.Sprintf("func NewJSONServer(idl *barrister.Idl, forceASCII bool%s) barrister.Server {", ifaces))
-	line(b, 1, fmt.Sprintf("return NewServer(idl, &barrister.JsonSerializer{forceASCII}%s)", ifaceIdents))
+	line(b, 1, fmt.Sprintf("return NewServer(idl, &barrister.JsonSerializer{ForceASCII: forceASCII}%s)", ifaceIdents))
 	line(b, 0, "}\n")
 
 	line(b, 0, fmt.Sprintf("func NewServer(idl *barrister.Idl, ser barrister.Serializer%s) barrister.Server {", ifaces))
 	line(b, 1, fmt.Sprintf("_svr := barrister.NewServer(idl, ser)"))
 	for _, name := range ifaceKeys {
-		lower := strings.ToLower(name)
-		line(b, 1, fmt.Sprintf("_svr.AddHandler(\"%s\", %s)", name, lower))
+		lower := escReserved(strings.ToLower(name))
+		if g.dispatch {
+			line(b, 1, fmt.Sprintf("_svr.AddDispatcher(\"%s\", %s, %sDispatcher{})", name, lower, g.naming(name)))
+		} else {
+			line(b, 1, fmt.Sprintf("_svr.AddHandler(\"%s\", %s)", name, lower))
+		}
 	}
 	line(b, 1, "return _svr")
 	line(b, 0, "}")
@@ -180,19 +683,24 @@ func (g *generateGo) generateInterface(b *bytes.Buffer, ifaceName string) {
 		panic("No interface found: " + ifaceName)
 	}
 
-	goName := capitalize(ifaceName)
+	goName := g.naming(ifaceName)
+	if iface, ok := g.idl.Interface(ifaceName); ok {
+		comment(b, 0, iface.Comment)
+	}
 	line(b, 0, fmt.Sprintf("type %s interface {", goName))
 	for _, fn := range funcs {
-		goName = capitalize(fn.Name)
+		goName = g.naming(fn.Name)
+		fnKey := ifaceName + "." + fn.Name
 		params := ""
 		for x, p := range fn.Params {
 			if x > 0 {
 				params += ", "
 			}
-			params += fmt.Sprintf("%s %s", escReserved(p.Name), p.goType(g.idl, g.optionalToPtr, g.pkgName))
+			params += fmt.Sprintf("%s %s", escReserved(p.Name), g.goType(fnKey+"."+p.Name, p))
 		}
+		comment(b, 1, fn.Comment)
 		line(b, 1, fmt.Sprintf("%s(%s) (%s, error)",
-			goName, params, fn.Returns.goType(g.idl, g.optionalToPtr, g.pkgName)))
+			goName, params, g.goType(fnKey, fn.Returns)))
 	}
 }
 
@@ -202,62 +710,240 @@ func (g *generateGo) generateProxy(b *bytes.Buffer, ifaceName string) {
 		panic("No interface found: " + ifaceName)
 	}
 
-	goIfaceName := capitalize(ifaceName)
+	goIfaceName := g.naming(ifaceName)
 	goName := goIfaceName + "Proxy"
 
-	line(b, 0, fmt.Sprintf("func New%s(c barrister.Client) %s { return %s{c, barrister.MustParseIdlJson([]byte(IdlJsonRaw))} }\n", goName, goIfaceName, goName))
+	line(b, 0, fmt.Sprintf("func New%s(c barrister.Client) %s { return %s{c} }\n", goName, goIfaceName, goName))
 
 	line(b, 0, fmt.Sprintf("type %s struct {", goName))
 	line(b, 1, "client barrister.Client")
-	line(b, 1, "idl    *barrister.Idl")
 	line(b, 0, "}\n")
 	for _, fn := range funcs {
 		method := fmt.Sprintf("%s.%s", ifaceName, fn.Name)
-		retType := fn.Returns.goType(g.idl, g.optionalToPtr, g.pkgName)
-		zeroVal := fn.Returns.zeroVal(g.idl, g.optionalToPtr, g.pkgName)
-		fnName := capitalize(fn.Name)
+		retType := g.goType(method, fn.Returns)
+		zeroVal := g.zeroVal(method, fn.Returns)
+		fnName := g.naming(fn.Name)
 		params := ""
 		paramIdents := ""
+		encoded := []string{}
 		for x, p := range fn.Params {
 			if x > 0 {
 				params += ", "
 			}
 			ident := escReserved(p.Name)
-			params += fmt.Sprintf("%s %s", ident, p.goType(g.idl, g.optionalToPtr, g.pkgName))
+			params += fmt.Sprintf("%s %s", ident, g.goType(method+"."+p.Name, p))
 			paramIdents += ", "
-			paramIdents += ident
+			if g.usesMapping(method+"."+p.Name, p, map[string]bool{}) {
+				encoded = append(encoded, ident)
+				paramIdents += "_enc_" + ident
+			} else {
+				paramIdents += ident
+			}
 		}
+		comment(b, 0, fn.Comment)
 		line(b, 0, fmt.Sprintf("func (_p %s) %s(%s) (%s, error) {",
 			goName, fnName, params, retType))
+		for _, ident := range encoded {
+			line(b, 1, fmt.Sprintf("_enc_%s, _err := BarristerIdl().EncodeValue(%s)", ident, ident))
+			line(b, 1, "if _err != nil {")
+			line(b, 2, fmt.Sprintf("return %s, _err", zeroVal))
+			line(b, 1, "}")
+		}
 		line(b, 1, fmt.Sprintf("_res, _err := _p.client.Call(\"%s\"%s)",
 			method, paramIdents))
-		line(b, 1, "if _err == nil {")
-		if g.optionalToPtr && fn.Returns.Optional {
-			line(b, 2, "if _res == nil {")
-			line(b, 3, "return nil, nil")
-			line(b, 2, "}")
-		}
-		line(b, 2, fmt.Sprintf("_retType := _p.idl.Method(\"%s\").Returns", method))
-		line(b, 2, fmt.Sprintf("_res, _err = barrister.Convert(_p.idl, &_retType, reflect.TypeOf(%s), _res, \"\")", zeroVal))
-		line(b, 1, "}")
-		line(b, 1, "if _err == nil {")
-		line(b, 2, fmt.Sprintf("_cast, _ok := _res.(%s)", retType))
-		line(b, 2, "if !_ok {")
-		line(b, 3, "_t := reflect.TypeOf(_res)")
-		line(b, 3, `_msg := fmt.Sprintf("`+method+` returned invalid type: %v", _t)`)
-		line(b, 3, fmt.Sprintf("return %s, &barrister.JsonRpcError{Code: -32000, Message: _msg}", zeroVal))
-		line(b, 2, "}")
-		line(b, 2, "return _cast, nil")
+		line(b, 1, "if _err != nil {")
+		line(b, 2, fmt.Sprintf("return %s, _err", zeroVal))
 		line(b, 1, "}")
-		line(b, 1, fmt.Sprintf("return %s, _err", zeroVal))
+		g.generateResult(b, 1, method, fn.Returns)
+		line(b, 0, "}\n")
+	}
+}
+
+// generateResult writes code that decodes _res, the result of a call to
+// method, and returns it and an error
+func (g *generateGo) generateResult(b *bytes.Buffer, level int, method string, ret Field) {
+	retType := g.goType(method, ret)
+	zeroVal := g.zeroVal(method, ret)
+
+	if ret.Optional && g.genericOptionals {
+		line(b, level, "if _res == nil {")
+		line(b, level+1, fmt.Sprintf("return %s{Null: true}, nil", retType))
+		line(b, level, "}")
+	} else if ret.Optional {
+		line(b, level, "if _res == nil {")
+		line(b, level+1, fmt.Sprintf("return %s, nil", zeroVal))
+		line(b, level, "}")
+	}
+
+	if g.usesMapping(method, ret, map[string]bool{}) {
+		// mapped types are decoded by their TypeMapping
+		g.addStdImport("fmt")
+		g.addStdImport("reflect")
+		line(b, level, fmt.Sprintf("_retType := BarristerIdl().Method(\"%s\").Returns", method))
+		line(b, level, fmt.Sprintf("_conv, _err := barrister.Convert(BarristerIdl(), &_retType, reflect.TypeOf(%s), _res, \"\")", zeroVal))
+		line(b, level, "if _err != nil {")
+		line(b, level+1, fmt.Sprintf("return %s, _err", zeroVal))
+		line(b, level, "}")
+		line(b, level, fmt.Sprintf("_cast, _ok := _conv.(%s)", retType))
+		line(b, level, "if !_ok {")
+		line(b, level+1, "_t := reflect.TypeOf(_conv)")
+		line(b, level+1, `_msg := fmt.Sprintf("`+method+` returned invalid type: %v", _t)`)
+		line(b, level+1, fmt.Sprintf("return %s, &barrister.JsonRpcError{Code: -32000, Message: _msg}", zeroVal))
+		line(b, level, "}")
+		line(b, level, "return _cast, nil")
+		return
+	}
+
+	line(b, level, fmt.Sprintf("var _ret %s", retType))
+	g.generateDecodeValue(b, level, "_ret", retType, ret, "_res", func(err string) string {
+		return fmt.Sprintf("return %s, %s", zeroVal, err)
+	})
+	line(b, level, "return _ret, nil")
+}
+
+// generateBatch writes a type that queues typed calls to the interface on a
+// barrister.Batch
+func (g *generateGo) generateBatch(b *bytes.Buffer, ifaceName string) {
+	funcs := g.idl.interfaces[ifaceName]
+	goIfaceName := g.naming(ifaceName)
+	goName := goIfaceName + "Batch"
+
+	line(b, 0, fmt.Sprintf("// %s queues calls to %s on a barrister.Batch.  Each call returns a", goName, goIfaceName))
+	line(b, 0, "// Future that holds its result once the Batch has been sent.")
+	line(b, 0, fmt.Sprintf("type %s struct {", goName))
+	line(b, 1, "*barrister.Batch")
+	line(b, 0, "}\n")
+
+	line(b, 0, fmt.Sprintf("// New%s returns a %s that queues calls on batch", goName, goName))
+	line(b, 0, fmt.Sprintf("func New%s(batch *barrister.Batch) %s { return %s{batch} }\n", goName, goName, goName))
+
+	for _, fn := range funcs {
+		method := ifaceName + "." + fn.Name
+		retType := g.goType(method, fn.Returns)
+		params, idents := g.mockParams(ifaceName, fn)
+		for x, p := range fn.Params {
+			if g.usesMapping(method+"."+p.Name, p, map[string]bool{}) {
+				idents[x] = "_enc_" + idents[x]
+			}
+		}
+
+		comment(b, 0, fn.Comment)
+		line(b, 0, fmt.Sprintf("func (_b %s) %s(%s) *barrister.Future[%s] {", goName, g.naming(fn.Name), params, retType))
+		for x, p := range fn.Params {
+			if strings.HasPrefix(idents[x], "_enc_") {
+				ident := escReserved(p.Name)
+				line(b, 1, fmt.Sprintf("_enc_%s, _err := BarristerIdl().EncodeValue(%s)", ident, ident))
+				line(b, 1, "if _err != nil {")
+				line(b, 2, fmt.Sprintf("return barrister.FailedFuture[%s](_err)", retType))
+				line(b, 1, "}")
+			}
+		}
+		line(b, 1, fmt.Sprintf("_params := []interface{}{%s}", strings.Join(idents, ", ")))
+		line(b, 1, fmt.Sprintf("return barrister.AddCall(_b.Batch, %q, _params, func(_res interface{}) (%s, error) {", method, retType))
+		g.generateResult(b, 2, method, fn.Returns)
+		line(b, 1, "})")
 		line(b, 0, "}\n")
 	}
 }
 
+// goName returns the Go type name for the IDL struct, enum or interface name
+func (g *generateGo) goName(idlName string) string {
+	return goNameStripMatchingPkg(idlName, g.pkgName, g.naming)
+}
+
+// typeMapping returns the custom type mapping for a field, or nil if the field
+// uses the default Go type.  key identifies the field (e.g. "Person.email")
+func (g *generateGo) typeMapping(key string, f Field) *TypeMapping {
+	reg := g.idl.typeRegistry()
+	if !reg.hasIdlKeys() {
+		return nil
+	}
+	m := reg.forIdl(key, f.Type)
+	if m != nil && m.GoImport != "" && !stringInSlice(m.GoImport, g.typeImports) {
+		g.typeImports = append(g.typeImports, m.GoImport)
+	}
+	return m
+}
+
+// goType returns the Go type for the field, taking custom type mappings into account
+func (g *generateGo) goType(key string, f Field) string {
+	if f.Optional && g.genericOptionals {
+		g.importBarrister = true
+		inner := f
+		inner.Optional = false
+		return fmt.Sprintf("barrister.Optional[%s]", g.goType(key, inner))
+	}
+
+	m := g.typeMapping(key, f)
+	if m == nil {
+		return f.goType(g.idl, g.optionalToPtr, g.pkgName, g.naming)
+	}
+
+	prefix := ""
+	if f.IsArray {
+		prefix = "[]"
+	}
+	if f.Optional && g.optionalToPtr {
+		prefix = "*" + prefix
+	}
+	return prefix + m.GoName
+}
+
+// zeroVal returns the Go zero value for the field, taking custom type mappings into account
+func (g *generateGo) zeroVal(key string, f Field) interface{} {
+	if f.Optional && g.genericOptionals {
+		return g.goType(key, f) + "{}"
+	}
+
+	m := g.typeMapping(key, f)
+	if m == nil {
+		return f.zeroVal(g.idl, g.optionalToPtr, g.pkgName, g.naming)
+	}
+
+	if f.Optional && g.optionalToPtr {
+		return "nil"
+	}
+	if f.IsArray {
+		return g.goType(key, f) + "{}"
+	}
+	return fmt.Sprintf("*new(%s)", m.GoName)
+}
+
+// usesMapping returns true if the field, or any struct field reachable from it,
+// uses a custom type mapping
+func (g *generateGo) usesMapping(key string, f Field, seen map[string]bool) bool {
+	if g.typeMapping(key, f) != nil {
+		return true
+	}
+
+	s, ok := g.idl.structs[f.Type]
+	if !ok || seen[s.Name] {
+		return false
+	}
+	seen[s.Name] = true
+	// fields are keyed by the struct that declares them, so a mapping on an
+	// inherited field is found by walking the parent, which is then also
+	// decoded without UnmarshalIdl
+	for _, sf := range s.Fields {
+		if g.usesMapping(s.Name+"."+sf.Name, sf, seen) {
+			return true
+		}
+	}
+	return s.Extends != "" && g.usesMapping("", Field{Type: s.Extends}, seen)
+}
+
+// comment writes an IDL comment as a Go comment.  Blank lines are kept
+// so paragraphs are preserved by godoc.
 func comment(b *bytes.Buffer, level int, comment string) {
+	comment = strings.TrimSpace(comment)
 	if comment != "" {
 		for _, ln := range strings.Split(comment, "\n") {
-			line(b, level, fmt.Sprintf("// %s", ln))
+			ln = strings.TrimRight(ln, " \t\r")
+			if ln == "" {
+				line(b, level, "//")
+			} else {
+				line(b, level, "// "+ln)
+			}
 		}
 	}
 }
@@ -0,0 +1,120 @@
+package barrister
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestGeneratedCodeVets generates conform/conform.json with each combination
+// of options that changes the generated code, and an IDL with custom type
+// mappings, and runs go vet on the code in a temporary GOPATH
+func TestGeneratedCodeVets(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go not found")
+	}
+	if testing.Short() {
+		t.Skip("skipping go vet of generated code in short mode")
+	}
+
+	root, err := filepath.Abs(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bench, err := ioutil.ReadFile("testdata/proxy_bench_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conform := MustParseIdlJson(readFile("conform/conform.json"))
+	mapped := createInheritedTypeMapIdl()
+
+	tests := []struct {
+		name string
+		idl  *Idl
+		opts GoOptions
+	}{
+		{"default", conform, GoOptions{}},
+		{"mixedcaps", conform, GoOptions{Naming: MixedCapsNaming}},
+		{"ptr", conform, GoOptions{OptionalToPtr: true}},
+		{"generic-optionals", conform, GoOptions{GenericOptionals: true}},
+		{"allow-unknown-enums", conform, GoOptions{AllowUnknownEnums: true}},
+		{"dispatch mocks", conform, GoOptions{Dispatchers: true, Mocks: true}},
+		{"ptr dispatch mocks", conform, GoOptions{OptionalToPtr: true, Dispatchers: true, Mocks: true, Naming: MixedCapsNaming}},
+		{"generic-optionals dispatch mocks", conform, GoOptions{GenericOptionals: true, Dispatchers: true, Mocks: true, Naming: MixedCapsNaming}},
+		{"mapped", mapped, GoOptions{}},
+		{"mapped dispatch mocks", mapped, GoOptions{Dispatchers: true, Mocks: true}},
+		{"mapped generic-optionals dispatch", mapped, GoOptions{GenericOptionals: true, Dispatchers: true}},
+	}
+	for _, test := range tests {
+		gopath, err := ioutil.TempDir("", "barrister-gopath")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(gopath)
+
+		src := filepath.Join(gopath, "src")
+		err = os.MkdirAll(filepath.Join(src, "github.com", "coopernurse"), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Symlink(root, filepath.Join(src, "github.com", "coopernurse", "barrister-go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		files := map[string][]byte{}
+		opts := test.opts
+		opts.PkgName = "mapped"
+		opts.BaseImport = "example.com/gen/"
+		if test.idl == conform {
+			opts.PkgName = "conform"
+			files[filepath.Join("conform", "proxy_bench_test.go")] = bench
+		}
+		pkgNameToGoCode, err := test.idl.GenerateGoWithOptions(opts)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		for pkg, code := range pkgNameToGoCode {
+			files[filepath.Join(pkg, pkg+".go")] = code
+		}
+		for fname, data := range files {
+			path := filepath.Join(src, "example.com", "gen", fname)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cmd := exec.Command(gobin, "vet", "example.com/gen/...")
+		cmd.Dir = gopath
+		cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off", "GOFLAGS=")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("%s: go vet failed: %s\n%s", test.name, err, out)
+		}
+	}
+}
+
+// createInheritedTypeMapIdl returns an IDL with a custom type mapping on a
+// field that a struct inherits
+func createInheritedTypeMapIdl() *Idl {
+	idl := NewBuilder().
+		Struct("Base").Field("id", "string").Field("created", "string").
+		Struct("Child").Extends("Base").Field("name", "string").
+		Interface("Svc").
+		Function("get", "Child", "id string").
+		Function("put", "bool", "c Child").
+		MustBuild()
+	idl.Types = NewTypeRegistry()
+	err := idl.Types.MapIdl("Base.created", TypeMapping{GoName: "time.Time", GoImport: "time"})
+	if err != nil {
+		panic(err)
+	}
+	return idl
+}
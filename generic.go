@@ -0,0 +1,222 @@
+package barrister
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Call calls method on c and converts the result to T using Convert and the
+// function's return type in idl.  Params are encoded with idl.EncodeValue,
+// so custom type mappings are supported.
+//
+// Client has no context support, so ctx is only checked before the call is
+// made.
+func Call[T any](ctx context.Context, c Client, idl *Idl, method string, params ...interface{}) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	fn, ok := idl.methods[method]
+	if !ok {
+		return zero, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("Unsupported method: %s", method)}
+	}
+	if len(fn.Params) != len(params) {
+		return zero, &JsonRpcError{Code: -32602,
+			Message: fmt.Sprintf("Method %s expects %d params but was passed %d", method, len(fn.Params), len(params))}
+	}
+
+	encoded := make([]interface{}, len(params))
+	for x, param := range params {
+		enc, err := idl.EncodeValue(param)
+		if err != nil {
+			return zero, err
+		}
+		encoded[x] = enc
+	}
+
+	res, err := c.Call(method, encoded...)
+	if err != nil {
+		return zero, err
+	}
+	if res == nil && fn.Returns.Optional {
+		return zero, nil
+	}
+
+	conv, err := Convert(idl, &fn.Returns, reflect.TypeOf(&zero).Elem(), res, "")
+	if err != nil {
+		return zero, err
+	}
+	return conv.(T), nil
+}
+
+type headersKey struct{}
+
+// HeadersFromContext returns the Headers of the request that a func
+// registered with RegisterFunc0 to RegisterFunc4 was called for
+func HeadersFromContext(ctx context.Context) (Headers, bool) {
+	h, ok := ctx.Value(headersKey{}).(Headers)
+	return h, ok
+}
+
+// RegisterFunc0 registers fn as the handler for method, an IDL function
+// with no params qualified with its interface, e.g. "Status.ping".
+// RegisterFunc1 to RegisterFunc4 do the same for functions with 1 to 4
+// params.  The first param of fn is a Context that holds the request's
+// Headers (see HeadersFromContext).
+//
+// Like AddHandler, they panic if fn's param and return types don't match the
+// IDL, so mistakes are found when the Server is created.  The functions of
+// an interface may be registered one at a time, but not combined with
+// AddHandler or AddDispatcher for the same interface.
+func RegisterFunc0[R any](s *Server, method string, fn func(context.Context) (R, error)) {
+	registerFunc(s, method, fn, func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error) {
+		return fn(ctx)
+	})
+}
+
+// RegisterFunc1 registers a handler for a function with one param.  See
+// RegisterFunc0.
+func RegisterFunc1[P1, R any](s *Server, method string, fn func(context.Context, P1) (R, error)) {
+	registerFunc(s, method, fn, func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error) {
+		f := idl.methods[method]
+		p1, err := convertParam[P1](idl, f, params, 0)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, p1)
+	})
+}
+
+// RegisterFunc2 registers a handler for a function with two params.  See
+// RegisterFunc0.
+func RegisterFunc2[P1, P2, R any](s *Server, method string, fn func(context.Context, P1, P2) (R, error)) {
+	registerFunc(s, method, fn, func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error) {
+		f := idl.methods[method]
+		p1, err := convertParam[P1](idl, f, params, 0)
+		if err != nil {
+			return nil, err
+		}
+		p2, err := convertParam[P2](idl, f, params, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, p1, p2)
+	})
+}
+
+// RegisterFunc3 registers a handler for a function with three params.  See
+// RegisterFunc0.
+func RegisterFunc3[P1, P2, P3, R any](s *Server, method string, fn func(context.Context, P1, P2, P3) (R, error)) {
+	registerFunc(s, method, fn, func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error) {
+		f := idl.methods[method]
+		p1, err := convertParam[P1](idl, f, params, 0)
+		if err != nil {
+			return nil, err
+		}
+		p2, err := convertParam[P2](idl, f, params, 1)
+		if err != nil {
+			return nil, err
+		}
+		p3, err := convertParam[P3](idl, f, params, 2)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, p1, p2, p3)
+	})
+}
+
+// RegisterFunc4 registers a handler for a function with four params.  See
+// RegisterFunc0.
+func RegisterFunc4[P1, P2, P3, P4, R any](s *Server, method string, fn func(context.Context, P1, P2, P3, P4) (R, error)) {
+	registerFunc(s, method, fn, func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error) {
+		f := idl.methods[method]
+		p1, err := convertParam[P1](idl, f, params, 0)
+		if err != nil {
+			return nil, err
+		}
+		p2, err := convertParam[P2](idl, f, params, 1)
+		if err != nil {
+			return nil, err
+		}
+		p3, err := convertParam[P3](idl, f, params, 2)
+		if err != nil {
+			return nil, err
+		}
+		p4, err := convertParam[P4](idl, f, params, 3)
+		if err != nil {
+			return nil, err
+		}
+		return fn(ctx, p1, p2, p3, p4)
+	})
+}
+
+// convertParam converts param i to P
+func convertParam[P any](idl *Idl, f Function, params []interface{}, i int) (P, error) {
+	var p P
+	conv, err := Convert(idl, &f.Params[i], reflect.TypeOf(&p).Elem(), params[i], "")
+	if err != nil {
+		return p, ParamError(err, i)
+	}
+	return conv.(P), nil
+}
+
+// boundFunc calls a registered func with params decoded by the Serializer
+type boundFunc func(ctx context.Context, idl *Idl, params []interface{}) (interface{}, error)
+
+// funcHandler is the handler of an interface whose functions were
+// registered with RegisterFunc.  It is Cloneable so that each request gets
+// a copy holding its Headers.
+type funcHandler struct {
+	funcs   map[string]boundFunc
+	headers Headers
+}
+
+func (h *funcHandler) CloneForReq(headers Headers) interface{} {
+	return &funcHandler{h.funcs, headers}
+}
+
+// funcDispatcher is the Dispatcher of interfaces with a funcHandler
+type funcDispatcher struct{}
+
+func (funcDispatcher) Dispatch(idl *Idl, handler interface{}, function string, params []interface{}) (interface{}, error) {
+	h := handler.(*funcHandler)
+	fn, ok := h.funcs[function]
+	if !ok {
+		return nil, &JsonRpcError{Code: -32601, Message: fmt.Sprintf("No func registered for function: %s", function)}
+	}
+	ctx := context.WithValue(context.Background(), headersKey{}, h.headers)
+	return fn(ctx, idl, params)
+}
+
+// registerFunc checks the type of fn against the IDL function named method,
+// and registers call to handle it
+func registerFunc(s *Server, method string, fn interface{}, call boundFunc) {
+	idlFunc, ok := s.idl.methods[method]
+	if !ok {
+		panic(fmt.Sprintf("barrister: IDL has no function: %s", method))
+	}
+
+	fnType := reflect.TypeOf(fn)
+	if fnType.NumIn()-1 != len(idlFunc.Params) {
+		msg := fmt.Sprintf("barrister: %s func accepts %d params but IDL specifies %d", method, fnType.NumIn()-1, len(idlFunc.Params))
+		panic(msg)
+	}
+	for x, param := range idlFunc.Params {
+		s.validate(param, fnType.In(x+1), fmt.Sprintf("%s param[%d]", method, x))
+	}
+	s.validate(idlFunc.Returns, fnType.Out(0), fmt.Sprintf("%s return value[0]", method))
+
+	iface, _ := parseMethod(method, LegacyNaming)
+	h, ok := s.handlers[iface].(*funcHandler)
+	if !ok {
+		if _, exists := s.handlers[iface]; exists {
+			panic(fmt.Sprintf("barrister: %s already has a handler registered with AddHandler or AddDispatcher", iface))
+		}
+		h = &funcHandler{funcs: map[string]boundFunc{}}
+		s.handlers[iface] = h
+		s.dispatchers[iface] = funcDispatcher{}
+	}
+	h.funcs[idlFunc.Name] = call
+}
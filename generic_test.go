@@ -0,0 +1,129 @@
+package barrister
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func newFuncServer() Server {
+	svr := NewJSONServer(parseTestIdl(), true)
+	RegisterFunc2(&svr, "A.add", func(ctx context.Context, a int64, b int64) (int64, error) {
+		return a + b, nil
+	})
+	RegisterFunc1(&svr, "A.repeat", func(ctx context.Context, req RepeatRequest) (RepeatResponse, error) {
+		items := []string{}
+		for i := int64(0); i < req.Count; i++ {
+			items = append(items, req.To_repeat)
+		}
+		return RepeatResponse{StatusOk, int(req.Count), items}, nil
+	})
+	RegisterFunc1(&svr, "B.echo", func(ctx context.Context, s string) (*string, error) {
+		if s == "return-null" {
+			return nil, nil
+		}
+		if s == "get-header" {
+			h, ok := HeadersFromContext(ctx)
+			if !ok {
+				return nil, &JsonRpcError{Code: -32000, Message: "no headers"}
+			}
+			s = h.Request["X-Test"][0]
+		}
+		return &s, nil
+	})
+	return svr
+}
+
+func TestRegisterFuncAndCall(t *testing.T) {
+	svr := newFuncServer()
+	idl := svr.idl
+	ctx := context.Background()
+	client := NewRemoteClient(&ServerTransport{Server: &svr, Headers: map[string][]string{"X-Test": {"hdr"}}}, false)
+
+	sum, err := Call[int64](ctx, client, idl, "A.add", 1, 2)
+	Equals(t, err, nil)
+	Equals(t, sum, int64(3))
+
+	rr, err := Call[decodeRepeatResponse](ctx, client, idl, "A.repeat", RepeatRequest{"hi", 2, false})
+	Equals(t, err, nil)
+	DeepEquals(t, rr, decodeRepeatResponse{StatusOk, 2, []string{"hi", "hi"}})
+
+	s, err := Call[*string](ctx, client, idl, "B.echo", "return-null")
+	Equals(t, err, nil)
+	True(t, s == nil)
+
+	s, err = Call[*string](ctx, client, idl, "B.echo", "get-header")
+	Equals(t, err, nil)
+	Equals(t, *s, "hdr")
+
+	// invalid params are reported by the server
+	_, err = Call[int64](ctx, client, idl, "A.add", "x", 2)
+	Equals(t, err.(*JsonRpcError).Code, -32602)
+
+	// functions without a registered func
+	_, err = Call[float64](ctx, client, idl, "A.sqrt", 4.0)
+	Equals(t, err.(*JsonRpcError).Code, -32601)
+
+	// the result doesn't convert to T
+	_, err = Call[string](ctx, client, idl, "A.add", 1, 2)
+	NotEquals(t, err, nil)
+
+	// errors found before the call is made
+	_, err = Call[int64](ctx, client, idl, "A.add", 1)
+	Equals(t, err.(*JsonRpcError).Code, -32602)
+	_, err = Call[int64](ctx, client, idl, "A.foo")
+	Equals(t, err.(*JsonRpcError).Code, -32601)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = Call[int64](cancelled, client, idl, "A.add", 1, 2)
+	Equals(t, err, context.Canceled)
+}
+
+func TestRegisterFuncPanics(t *testing.T) {
+	cases := []struct {
+		msg      string
+		register func(svr *Server)
+	}{
+		{"IDL has no function: A.foo", func(svr *Server) {
+			RegisterFunc0(svr, "A.foo", func(ctx context.Context) (string, error) { return "", nil })
+		}},
+		{"A.add func accepts 1 params but IDL specifies 2", func(svr *Server) {
+			RegisterFunc1(svr, "A.add", func(ctx context.Context, a int64) (int64, error) { return a, nil })
+		}},
+		{"A.add param[1] has invalid type: string", func(svr *Server) {
+			RegisterFunc2(svr, "A.add", func(ctx context.Context, a int64, b string) (int64, error) { return a, nil })
+		}},
+		{"A.add return value[0] has invalid type: bool", func(svr *Server) {
+			RegisterFunc2(svr, "A.add", func(ctx context.Context, a int64, b int64) (bool, error) { return false, nil })
+		}},
+		{"B already has a handler", func(svr *Server) {
+			svr.AddHandler("B", BImpl{})
+			RegisterFunc1(svr, "B.echo", func(ctx context.Context, s string) (*string, error) { return nil, nil })
+		}},
+	}
+
+	for _, c := range cases {
+		func() {
+			defer func() {
+				r := recover()
+				if r == nil || !strings.Contains(r.(string), c.msg) {
+					t.Errorf("expected panic containing %q, got: %v", c.msg, r)
+				}
+			}()
+			svr := NewJSONServer(parseTestIdl(), true)
+			c.register(&svr)
+		}()
+	}
+}
+
+func TestAddHandlerReplacesFuncs(t *testing.T) {
+	svr := newFuncServer()
+	svr.AddHandler("B", BImpl{})
+
+	res, err := svr.Call(newHeaders(), "B.echo", "hi")
+	Equals(t, err, nil)
+	Equals(t, *res.(*string), "hi")
+}
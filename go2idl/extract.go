@@ -0,0 +1,511 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// goPackage holds the type and const declarations of a parsed Go package
+type goPackage struct {
+	name  string
+	types map[string]*typeDecl
+
+	// string constants, keyed by the name of their named type
+	consts map[string][]enumConst
+}
+
+type typeDecl struct {
+	pkg  *goPackage
+	spec *ast.TypeSpec
+	doc  string
+}
+
+type enumConst struct {
+	value string
+	doc   string
+}
+
+// extractor converts Go interfaces, and the types they reference, to IDL
+type extractor struct {
+	fset *token.FileSet
+	pkgs map[string]*goPackage
+	b    *barrister.Builder
+
+	// declarations of the types that have been added to b,
+	// keyed by IDL name
+	done map[string]*typeDecl
+
+	errs []string
+}
+
+func newExtractor() *extractor {
+	return &extractor{
+		fset: token.NewFileSet(),
+		pkgs: map[string]*goPackage{},
+		b:    barrister.NewBuilder(),
+		done: map[string]*typeDecl{},
+	}
+}
+
+// loadDir parses the non-test Go files in dir
+func (e *extractor) loadDir(dir string) error {
+	notTest := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}
+	pkgs, err := parser.ParseDir(e.fset, dir, notTest, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		pkg, ok := e.pkgs[name]
+		if !ok {
+			pkg = &goPackage{name: name, types: map[string]*typeDecl{}, consts: map[string][]enumConst{}}
+			e.pkgs[name] = pkg
+		}
+
+		files := []string{}
+		for fname := range pkgs[name].Files {
+			files = append(files, fname)
+		}
+		sort.Strings(files)
+		for _, fname := range files {
+			e.loadFile(pkg, pkgs[name].Files[fname])
+		}
+	}
+	return nil
+}
+
+func (e *extractor) loadFile(pkg *goPackage, file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				doc := s.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				pkg.types[s.Name.Name] = &typeDecl{pkg, s, docText(doc)}
+			case *ast.ValueSpec:
+				if gen.Tok != token.CONST {
+					continue
+				}
+				typ, ok := s.Type.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				for _, v := range s.Values {
+					lit, ok := v.(*ast.BasicLit)
+					if !ok || lit.Kind != token.STRING {
+						continue
+					}
+					val, err := strconv.Unquote(lit.Value)
+					if err == nil {
+						pkg.consts[typ.Name] = append(pkg.consts[typ.Name], enumConst{val, docText(s.Doc)})
+					}
+				}
+			}
+		}
+	}
+}
+
+func (e *extractor) errorf(pos token.Pos, format string, args ...interface{}) {
+	e.errs = append(e.errs, fmt.Sprintf("%s: %s", e.fset.Position(pos), fmt.Sprintf(format, args...)))
+}
+
+// findType returns the declaration of the named type.  name may be
+// qualified with a package name.
+func (e *extractor) findType(name string) *typeDecl {
+	pkgName, typeName := "", name
+	if i := strings.Index(name, "."); i > -1 {
+		pkgName, typeName = name[:i], name[i+1:]
+	}
+
+	pkgNames := []string{}
+	for n := range e.pkgs {
+		pkgNames = append(pkgNames, n)
+	}
+	sort.Strings(pkgNames)
+
+	for _, n := range pkgNames {
+		if pkgName == "" || pkgName == n {
+			if decl, ok := e.pkgs[n].types[typeName]; ok {
+				return decl
+			}
+		}
+	}
+	return nil
+}
+
+// addInterface adds the interface with the given name to the IDL
+func (e *extractor) addInterface(name string) {
+	decl := e.findType(name)
+	if decl == nil {
+		e.errs = append(e.errs, fmt.Sprintf("interface not found: %s", name))
+		return
+	}
+	iface, ok := decl.spec.Type.(*ast.InterfaceType)
+	if !ok {
+		e.errorf(decl.spec.Pos(), "%s is not an interface", name)
+		return
+	}
+
+	type function struct {
+		name, returns, doc string
+		params             []string
+	}
+	funcs := []function{}
+
+	for _, m := range iface.Methods.List {
+		if len(m.Names) == 0 {
+			e.errorf(m.Pos(), "embedded interfaces are not supported")
+			continue
+		}
+		ft := m.Type.(*ast.FuncType)
+		goName := m.Names[0].Name
+		if !ast.IsExported(goName) {
+			continue
+		}
+
+		fn := function{name: lowerCamel(goName), doc: docText(m.Doc)}
+		ok := true
+
+		params := fieldList(ft.Params)
+		if len(params) > 0 && isContext(params[0].Type) {
+			params = params[1:]
+		}
+		for i, p := range params {
+			pname := p.name
+			if pname == "" || pname == "_" {
+				pname = fmt.Sprintf("p%d", i)
+			}
+			typ, ptr, valid := e.idlType(decl.pkg, p.Type)
+			if !valid {
+				ok = false
+				continue
+			}
+			if ptr {
+				e.errorf(p.Type.Pos(), "%s.%s param %s: pointer params are not supported, as IDL params can't be optional", name, goName, pname)
+				ok = false
+				continue
+			}
+			fn.params = append(fn.params, pname+" "+typ)
+		}
+
+		results := fieldList(ft.Results)
+		if len(results) != 2 || !isError(results[1].Type) {
+			e.errorf(m.Pos(), "%s.%s must return a value and an error", name, goName)
+			continue
+		}
+		typ, ptr, valid := e.idlType(decl.pkg, results[0].Type)
+		if !valid || !ok {
+			continue
+		}
+		if ptr {
+			typ += " [optional]"
+		}
+		fn.returns = typ
+		funcs = append(funcs, fn)
+	}
+
+	ib := e.b.Interface(name[strings.LastIndex(name, ".")+1:]).Comment(decl.doc)
+	for _, fn := range funcs {
+		ib.Function(fn.name, fn.returns, fn.params...).Comment(fn.doc)
+	}
+}
+
+type param struct {
+	name string
+	Type ast.Expr
+}
+
+// fieldList flattens a parameter or result list, which may declare
+// more than one name per entry
+func fieldList(fl *ast.FieldList) []param {
+	params := []param{}
+	if fl == nil {
+		return params
+	}
+	for _, f := range fl.List {
+		if len(f.Names) == 0 {
+			params = append(params, param{"", f.Type})
+		}
+		for _, n := range f.Names {
+			params = append(params, param{n.Name, f.Type})
+		}
+	}
+	return params
+}
+
+// idlType returns the IDL type for expr, which is declared in pkg.  ptr
+// is true if expr is a pointer or a barrister.Optional.  If the type can't
+// be represented in IDL, an error is recorded and ok is false.
+func (e *extractor) idlType(pkg *goPackage, expr ast.Expr) (typ string, ptr bool, ok bool) {
+	if idx, isIdx := expr.(*ast.IndexExpr); isIdx && exprString(idx.X) == "barrister.Optional" {
+		expr = idx.Index
+		ptr = true
+	} else if star, isPtr := expr.(*ast.StarExpr); isPtr {
+		expr = star.X
+		ptr = true
+	}
+
+	if arr, isArr := expr.(*ast.ArrayType); isArr && arr.Len == nil {
+		if elt := exprString(arr.Elt); elt == "byte" || elt == "uint8" {
+			e.errorf(expr.Pos(), "unsupported type: %s (encoded as a base64 string)", exprString(expr))
+			return "", ptr, false
+		}
+		if _, nested := arr.Elt.(*ast.ArrayType); nested {
+			e.errorf(expr.Pos(), "unsupported type: %s (nested slices)", exprString(expr))
+			return "", ptr, false
+		}
+		elt, eltPtr, ok := e.idlType(pkg, arr.Elt)
+		if !ok {
+			return "", ptr, false
+		}
+		if eltPtr || strings.HasPrefix(elt, "[]") {
+			e.errorf(expr.Pos(), "unsupported type: %s", exprString(expr))
+			return "", ptr, false
+		}
+		return "[]" + elt, ptr, true
+	}
+
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if builtin, isBuiltin := builtinTypes[t.Name]; isBuiltin {
+			return builtin, ptr, true
+		}
+		if decl, found := pkg.types[t.Name]; found {
+			typ, ok = e.namedType(decl)
+			return typ, ptr, ok
+		}
+	case *ast.SelectorExpr:
+		if x, isIdent := t.X.(*ast.Ident); isIdent {
+			if other, found := e.pkgs[x.Name]; found {
+				if decl, found := other.types[t.Sel.Name]; found {
+					typ, ok = e.namedType(decl)
+					return typ, ptr, ok
+				}
+			}
+		}
+	}
+
+	e.errorf(expr.Pos(), "unsupported type: %s", exprString(expr))
+	return "", ptr, false
+}
+
+// namedType returns the IDL type for a declared Go type, adding structs
+// and enums to the IDL the first time they're seen
+func (e *extractor) namedType(decl *typeDecl) (string, bool) {
+	name := decl.spec.Name.Name
+	if prev, ok := e.done[name]; ok {
+		if prev != decl {
+			e.errorf(decl.spec.Pos(), "type %s conflicts with %s.%s, IDL type names must be unique", name, prev.pkg.name, name)
+			return "", false
+		}
+		return name, true
+	}
+
+	switch t := decl.spec.Type.(type) {
+	case *ast.StructType:
+		e.done[name] = decl
+		e.addStruct(decl, t)
+		return name, true
+	case *ast.Ident:
+		consts := decl.pkg.consts[name]
+		if t.Name == "string" && len(consts) > 0 {
+			e.done[name] = decl
+			eb := e.b.Enum(name).Comment(decl.doc)
+			for _, c := range consts {
+				eb.Value(c.value).Comment(c.doc)
+			}
+			return name, true
+		}
+	}
+
+	typ, ptr, ok := e.idlType(decl.pkg, decl.spec.Type)
+	if ok && ptr {
+		e.errorf(decl.spec.Pos(), "unsupported type: %s (named pointer)", name)
+		return "", false
+	}
+	return typ, ok
+}
+
+func (e *extractor) addStruct(decl *typeDecl, st *ast.StructType) {
+	name := decl.spec.Name.Name
+	sb := e.b.Struct(name).Comment(decl.doc)
+	extends := ""
+
+	for _, f := range st.Fields.List {
+		jsonName, omitEmpty, skip := jsonTag(f)
+		if skip {
+			continue
+		}
+
+		if len(f.Names) == 0 {
+			// an embedded struct becomes the parent struct
+			typ, ptr, ok := e.idlType(decl.pkg, f.Type)
+			if !ok {
+				continue
+			}
+			if parent := e.findType(typ); ptr || jsonName != "" || parent == nil || !isStruct(parent) {
+				e.errorf(f.Pos(), "%s: embedded field %s must be an untagged struct", name, exprString(f.Type))
+			} else if extends != "" {
+				e.errorf(f.Pos(), "%s: only one embedded struct is supported", name)
+			} else {
+				extends = typ
+				sb.Extends(typ)
+			}
+			continue
+		}
+
+		for _, n := range f.Names {
+			if !ast.IsExported(n.Name) {
+				continue
+			}
+			typ, ptr, ok := e.idlType(decl.pkg, f.Type)
+			if !ok {
+				continue
+			}
+
+			// encoding/json uses the Go name of untagged fields, so the
+			// IDL must too
+			fname := jsonName
+			if fname == "" {
+				fname = n.Name
+			}
+			if ptr || omitEmpty {
+				typ += " [optional]"
+			}
+			sb.Field(fname, typ).Comment(docText(f.Doc))
+		}
+	}
+}
+
+// jsonTag returns the name and omitempty option from the field's json tag,
+// and whether the field is excluded with `json:"-"`
+func jsonTag(f *ast.Field) (name string, omitEmpty bool, skip bool) {
+	if f.Tag == nil {
+		return "", false, false
+	}
+	tag, err := strconv.Unquote(f.Tag.Value)
+	if err != nil {
+		return "", false, false
+	}
+	val := reflect.StructTag(tag).Get("json")
+	if val == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(val, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+var builtinTypes = map[string]string{
+	"string":  "string",
+	"bool":    "bool",
+	"int":     "int",
+	"int8":    "int",
+	"int16":   "int",
+	"int32":   "int",
+	"int64":   "int",
+	"uint":    "int",
+	"uint8":   "int",
+	"uint16":  "int",
+	"uint32":  "int",
+	"uint64":  "int",
+	"float32": "float",
+	"float64": "float",
+}
+
+func isStruct(decl *typeDecl) bool {
+	_, ok := decl.spec.Type.(*ast.StructType)
+	return ok
+}
+
+func isContext(expr ast.Expr) bool {
+	return exprString(expr) == "context.Context"
+}
+
+func isError(expr ast.Expr) bool {
+	return exprString(expr) == "error"
+}
+
+// exprString returns the Go source for a type expression
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.IndexExpr:
+		return exprString(t.X) + "[" + exprString(t.Index) + "]"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + exprString(t.Elt)
+		}
+		return "[" + exprString(t.Len) + "]" + exprString(t.Elt)
+	case *ast.BasicLit:
+		return t.Value
+	case *ast.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	case *ast.InterfaceType:
+		return "interface{}"
+	case *ast.ChanType:
+		return "chan " + exprString(t.Value)
+	case *ast.FuncType:
+		return "func"
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Text())
+}
+
+// lowerCamel lower cases the first word of a Go identifier, so "SayHi"
+// becomes "sayHi" and "URLPath" becomes "urlPath"
+func lowerCamel(s string) string {
+	runes := []rune(s)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) && unicode.IsLower(runes[n]) {
+		// the last upper case letter starts the next word
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// go2idl generates Barrister IDL from Go interfaces.  The interfaces, and
+// the structs and string enums they reference, are read from the Go source
+// in the given package directories.
+func main() {
+	var ifaces string
+	var outfile string
+	var asJson bool
+
+	flag.StringVar(&ifaces, "i", "", "Comma separated list of Go interfaces to convert (required). e.g. UserService,auth.AuthService")
+	flag.StringVar(&outfile, "o", "", "File to write to.  Default is STDOUT")
+	flag.BoolVar(&asJson, "json", false, "Write IDL JSON instead of .idl source")
+	flag.Parse()
+
+	if ifaces == "" {
+		fmt.Fprintf(os.Stderr, "Usage: go2idl -i Interface[,Interface] [options] [package dir ...]\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	idl, errs := extract(dirs, strings.Split(ifaces, ","))
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+
+	var out []byte
+	if asJson {
+		b, err := idl.IdlJson()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing IDL JSON: %s\n", err)
+			os.Exit(1)
+		}
+		out = append(b, '\n')
+	} else {
+		out = idl.IdlSource("out")["out.idl"]
+	}
+
+	if outfile == "" {
+		os.Stdout.Write(out)
+	} else {
+		err := ioutil.WriteFile(outfile, out, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file %s: %s\n", outfile, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// extract loads the Go packages in dirs and converts the named interfaces
+// to IDL.  All unsupported types are returned as errors.
+func extract(dirs []string, ifaces []string) (*barrister.Idl, []string) {
+	e := newExtractor()
+	for _, dir := range dirs {
+		err := e.loadDir(dir)
+		if err != nil {
+			return nil, []string{err.Error()}
+		}
+	}
+
+	for _, name := range ifaces {
+		e.addInterface(strings.TrimSpace(name))
+	}
+	if len(e.errs) > 0 {
+		return nil, e.errs
+	}
+
+	idl, err := e.b.Build()
+	if err != nil {
+		return nil, strings.Split(err.Error(), "\n")
+	}
+	return idl, nil
+}
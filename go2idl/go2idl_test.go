@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestExtract(t *testing.T) {
+	idl, errs := extract([]string{"testdata/users", "testdata/common"}, []string{"UserService"})
+	if len(errs) > 0 {
+		t.Fatal(strings.Join(errs, "\n"))
+	}
+
+	Equals(t, string(idl.IdlSource("out")["out.idl"]), `// A user of the system
+struct User extends Base {
+    Name string
+    emails []string [optional]
+    Age int [optional]
+    verified bool [optional]
+    Status Status
+    Friends []User
+}
+
+struct Base {
+    id string
+}
+
+// Status of an operation
+enum Status {
+    ok
+    // something went wrong
+    err
+}
+
+// UserService manages users
+interface UserService {
+    // Get returns the user, or nil if not found
+    get(userID string) User [optional]
+    findByStatus(s Status, limit int) []User
+    setScore(userID string, score float) bool
+}
+`)
+}
+
+func TestExtractUnsupportedTypes(t *testing.T) {
+	_, errs := extract([]string{"testdata/bad"}, []string{"BadService", "Missing"})
+
+	expected := []string{
+		"testdata/bad/bad.go:6:8: unsupported type: time.Time",
+		"testdata/bad/bad.go:7:8: unsupported type: map[string]string",
+		"testdata/bad/bad.go:8:8: unsupported type: []byte (encoded as a base64 string)",
+		"testdata/bad/bad.go:12:9: BadService.Save param e: pointer params are not supported, as IDL params can't be optional",
+		"testdata/bad/bad.go:13:2: BadService.Touch must return a value and an error",
+		"interface not found: Missing",
+	}
+	Equals(t, len(errs), len(expected))
+	for i := 0; i < len(errs) && i < len(expected); i++ {
+		Equals(t, errs[i], expected[i])
+	}
+}
+
+func TestLowerCamel(t *testing.T) {
+	cases := map[string]string{
+		"SayHi":   "sayHi",
+		"ID":      "id",
+		"URLPath": "urlPath",
+		"UserID":  "userID",
+		"A":       "a",
+		"already": "already",
+	}
+	for in, expected := range cases {
+		Equals(t, lowerCamel(in), expected)
+	}
+}
@@ -0,0 +1,15 @@
+package bad
+
+import "time"
+
+type Event struct {
+	When  time.Time
+	Attrs map[string]string
+	Data  []byte
+}
+
+type BadService interface {
+	Save(e *Event) (bool, error)
+	Touch(id string) error
+	Load(id string) (Event, error)
+}
@@ -0,0 +1,14 @@
+package common
+
+// Status of an operation
+type Status string
+
+const (
+	StatusOK Status = "ok"
+	// something went wrong
+	StatusErr Status = "err"
+)
+
+type Base struct {
+	ID string `json:"id"`
+}
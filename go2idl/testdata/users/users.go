@@ -0,0 +1,32 @@
+package users
+
+import (
+	"context"
+
+	"common"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// A user of the system
+type User struct {
+	common.Base
+	Name     string
+	Emails   []string `json:"emails,omitempty"`
+	Age      *int64
+	Verified barrister.Optional[bool] `json:"verified,omitzero"`
+	Status   common.Status
+	Friends  []User
+	password string
+	Internal string `json:"-"`
+}
+
+type Score float64
+
+// UserService manages users
+type UserService interface {
+	// Get returns the user, or nil if not found
+	Get(ctx context.Context, userID string) (*User, error)
+	FindByStatus(s common.Status, limit int) ([]User, error)
+	SetScore(userID string, score Score) (bool, error)
+}
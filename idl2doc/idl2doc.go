@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/coopernurse/barrister-go/docgen"
+	"github.com/coopernurse/barrister-go/parser"
+)
+
+// idl2doc generates HTML or Markdown documentation from an IDL file
+func main() {
+	var outdir string
+	var format string
+	var title string
+	var quiet bool
+
+	flag.StringVar(&outdir, "d", "doc", "Directory to write generated files to")
+	flag.StringVar(&format, "format", docgen.HTML, "Output format: 'html' or 'markdown'")
+	flag.StringVar(&title, "title", "", "Title of the index page")
+	flag.BoolVar(&quiet, "q", false, "Enable quiet mode (no output)")
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: idl2doc [options] [jsonfile|idlfile]\n")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	idl, err := parser.LoadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", flag.Arg(0), err)
+		os.Exit(1)
+	}
+
+	files, err := docgen.Generate(idl, docgen.Options{Title: title, Format: format})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating documentation: %s\n", err)
+		os.Exit(1)
+	}
+
+	err = os.MkdirAll(outdir, 0755)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating dir %s: %s\n", outdir, err)
+		os.Exit(1)
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		outfile := filepath.Join(outdir, name)
+		if !quiet {
+			fmt.Printf("Generating %s\n", outfile)
+		}
+		err = ioutil.WriteFile(outfile, files[name], 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file %s: %s\n", outfile, err)
+			os.Exit(1)
+		}
+	}
+}
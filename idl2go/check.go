@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+// maxDiffLines is the number of changed lines shown per file by checkFile
+const maxDiffLines = 5
+
+var checksumRe = regexp.MustCompile(`const BarristerChecksum string = "([^"]*)"`)
+
+// checkFile compares a generated file with the file on disk.  If they
+// differ, a summary of the differences is returned, one line per element.
+// If the files are identical nil is returned.
+func checkFile(out output) []string {
+	existing, err := ioutil.ReadFile(out.path)
+	if os.IsNotExist(err) {
+		return []string{fmt.Sprintf("%s: missing", out.path)}
+	} else if err != nil {
+		return []string{fmt.Sprintf("%s: %s", out.path, err)}
+	}
+
+	if bytes.Equal(existing, out.data) {
+		return nil
+	}
+
+	msgs := []string{}
+	if m := checksumRe.FindSubmatch(existing); m != nil && out.checksum != "" && string(m[1]) != out.checksum {
+		msgs = append(msgs, fmt.Sprintf("%s: BarristerChecksum is %s, IDL checksum is %s", out.path, m[1], out.checksum))
+	}
+	return append(msgs, diffSummary(out.path, existing, out.data)...)
+}
+
+// diffSummary describes the range of lines that differ between old and new,
+// and shows the first few removed and added lines
+func diffSummary(path string, old []byte, new []byte) []string {
+	a := bytes.Split(old, []byte("\n"))
+	b := bytes.Split(new, []byte("\n"))
+
+	// skip the lines that are the same at the start and end of both files
+	start := 0
+	for start < len(a) && start < len(b) && bytes.Equal(a[start], b[start]) {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && bytes.Equal(a[endA-1], b[endB-1]) {
+		endA--
+		endB--
+	}
+
+	msgs := []string{fmt.Sprintf("%s: out of date: %d lines on disk replaced by %d generated lines at line %d",
+		path, endA-start, endB-start, start+1)}
+	msgs = append(msgs, diffLines("-", a[start:endA])...)
+	return append(msgs, diffLines("+", b[start:endB])...)
+}
+
+func diffLines(prefix string, lines [][]byte) []string {
+	msgs := []string{}
+	for i, ln := range lines {
+		if i == maxDiffLines {
+			msgs = append(msgs, fmt.Sprintf("  %s ... %d more", prefix, len(lines)-i))
+			break
+		}
+		msgs = append(msgs, fmt.Sprintf("  %s %s", prefix, ln))
+	}
+	return msgs
+}
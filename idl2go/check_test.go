@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestCheckFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "svc.go")
+	out := output{path, []byte("package svc\n\nconst BarristerChecksum string = \"new\"\n\nfunc A() {}\n"), "svc", "new"}
+
+	DeepEquals(t, checkFile(out), []string{path + ": missing"})
+
+	err = ioutil.WriteFile(path, out.data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(checkFile(out)), 0)
+
+	err = ioutil.WriteFile(path, []byte("package svc\n\nconst BarristerChecksum string = \"old\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	DeepEquals(t, checkFile(out), []string{
+		path + ": BarristerChecksum is old, IDL checksum is new",
+		path + ": out of date: 1 lines on disk replaced by 3 generated lines at line 3",
+		`  - const BarristerChecksum string = "old"`,
+		`  + const BarristerChecksum string = "new"`,
+		`  + `,
+		`  + func A() {}`,
+	})
+}
+
+func TestDiffSummaryLimit(t *testing.T) {
+	msgs := diffSummary("a.go", []byte("a\nb\n"), []byte("a\n1\n2\n3\n4\n5\n6\n7\nb\n"))
+	Equals(t, msgs[0], "a.go: out of date: 0 lines on disk replaced by 7 generated lines at line 2")
+	Equals(t, len(msgs), 1+maxDiffLines+1)
+	Equals(t, msgs[len(msgs)-1], "  + ... 2 more")
+}
@@ -1,102 +1,352 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/coopernurse/barrister-go"
+	"github.com/coopernurse/barrister-go/parser"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// typeFlags collects repeated -t flag values
+type typeFlags []string
+
+func (t *typeFlags) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *typeFlags) Set(s string) error {
+	*t = append(*t, s)
+	return nil
+}
+
+// output is a file generated by idl2go
+type output struct {
+	path string
+	data []byte
+
+	// pkg is the Go package name, or "" for non-Go output
+	pkg string
+
+	// checksum of the IDL the file was generated from
+	checksum string
+}
+
 func main() {
+	var types typeFlags
 	var outdir string
 	var defaultPkgName string
 	var baseImport string
 	var optionalToPtr bool
+	var genericOptionals bool
 	var quiet bool
 	var tostdout bool
 	var fromstdin bool
+	var naming string
+	var gen string
+	var check bool
+	var allowUnknownEnums bool
+	var dispatchers bool
+	var mocks bool
+	var skeleton string
 
 	flag.StringVar(&outdir, "d", ".", "Base directory to write generated .go files to")
-	flag.StringVar(&defaultPkgName, "p", "", "Package name to write to generated Go file")
+	flag.StringVar(&defaultPkgName, "p", "", "Package name to write to generated Go file.  Default is the IDL file name without its extension")
 	flag.StringVar(&baseImport, "b", "", "Base import path for imported namespaces")
 	flag.BoolVar(&optionalToPtr, "n", false, "If true, optional IDL fields will be generated as Go pointers")
+	flag.BoolVar(&genericOptionals, "generic-optionals", false, "If true, optional IDL fields and results will be generated as barrister.Optional[T].  Overrides -n")
 	flag.BoolVar(&quiet, "q", false, "Enable quiet mode (no output)")
 	flag.BoolVar(&tostdout, "s", false, "Write .go file to STDOUT (implies -q)")
 	flag.BoolVar(&fromstdin, "i", false, "Read IDL JSON from STDIN")
+	flag.StringVar(&naming, "names", "mixedcaps", "Go naming strategy: 'mixedcaps' (say_hi -> SayHi) or 'legacy' (say_hi -> Say_hi)")
+	flag.StringVar(&gen, "gen", "go", "Output to generate: 'go', 'jsonschema' (writes <package>.schema.json), 'openrpc' (writes <package>.openrpc.json) or 'typescript' (writes <package>.ts)")
+	flag.BoolVar(&allowUnknownEnums, "allow-unknown-enums", false, "If true, generated enum types will unmarshal values that aren't in the IDL")
+	flag.BoolVar(&dispatchers, "dispatch", false, "If true, a reflection-free Dispatcher will be generated for each interface and used by NewServer")
+	flag.BoolVar(&mocks, "mocks", false, "If true, a mock and an in-process fake Client will be generated for each interface")
+	flag.StringVar(&skeleton, "skeleton", "", "Directory to write a server skeleton to (package main, one file per interface plus main.go).  Existing files are never overwritten.  Requires -b and a single IDL file")
+	flag.BoolVar(&check, "check", false, "Don't write any files.  Exit with status 1 if the files on disk differ from the generated files")
+	flag.Var(&types, "t", "Map an IDL type or field to a Go type (repeatable). e.g. Person.created=time.Time")
 	flag.Parse()
 
-	if !fromstdin && flag.NArg() != 1 {
-		fmt.Fprintf(os.Stderr, "Usage: idl2go jsonfile\n")
+	if fromstdin == (flag.NArg() > 0) {
+		fmt.Fprintf(os.Stderr, "Usage: idl2go [options] [jsonfile|idlfile ...]\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	if tostdout {
+	if tostdout || check {
 		quiet = true
 	}
 
-	jsonFile := flag.Arg(0)
-	baseName := filepath.Base(jsonFile)
-	pos := strings.LastIndex(baseName, ".")
-	if pos > -1 {
-		baseName = baseName[0:pos]
+	opts := barrister.GoOptions{
+		BaseImport:        baseImport,
+		OptionalToPtr:     optionalToPtr,
+		GenericOptionals:  genericOptionals,
+		AllowUnknownEnums: allowUnknownEnums,
+		Dispatchers:       dispatchers,
+		Mocks:             mocks,
+	}
+	switch naming {
+	case "mixedcaps":
+		opts.Naming = barrister.MixedCapsNaming
+	case "legacy":
+		opts.Naming = barrister.LegacyNaming
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid -names value: %s\n", naming)
+		os.Exit(1)
 	}
 
-	if defaultPkgName == "" {
-		defaultPkgName = baseName
+	if skeleton != "" && (baseImport == "" || gen != "go") {
+		fmt.Fprintf(os.Stderr, "-skeleton requires -b and -gen go\n")
+		os.Exit(1)
 	}
 
-	if !quiet {
-		if fromstdin {
-			fmt.Println("Loading IDL from STDIN")
-		} else {
-			fmt.Println("Loading IDL from:", jsonFile)
+	switch gen {
+	case "go", "jsonschema", "openrpc", "typescript":
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid -gen value: %s\n", gen)
+		os.Exit(1)
+	}
+
+	var typeReg *barrister.TypeRegistry
+	if len(types) > 0 {
+		var err error
+		typeReg, err = parseTypeMappings(types)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error in -t flag: %s\n", err)
+			os.Exit(1)
 		}
 	}
 
-	idl, err := parseIdl(fromstdin, jsonFile)
-	if err != nil {
+	inputs := flag.Args()
+	if fromstdin {
+		inputs = []string{""}
+	}
+	if defaultPkgName != "" && len(inputs) > 1 {
+		fmt.Fprintf(os.Stderr, "-p can't be used with more than one IDL file\n")
+		os.Exit(1)
+	}
+	if skeleton != "" && len(inputs) > 1 {
+		fmt.Fprintf(os.Stderr, "-skeleton can't be used with more than one IDL file\n")
+		os.Exit(1)
+	}
+
+	outputs := []output{}
+	skeletons := []output{}
+	for _, jsonFile := range inputs {
 		from := jsonFile
 		if fromstdin {
 			from = "STDIN"
 		}
-		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", from, err)
+		if !quiet {
+			fmt.Println("Loading IDL from:", from)
+		}
+
+		idl, err := parseIdl(fromstdin, jsonFile)
+		if err != nil {
+			if verrs, ok := err.(barrister.ValidationErrors); ok {
+				fmt.Fprintf(os.Stderr, "Invalid IDL in %s:\n", from)
+				for _, verr := range verrs {
+					fmt.Fprintf(os.Stderr, "  %s\n", verr)
+				}
+			} else {
+				fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", from, err)
+			}
+			os.Exit(1)
+		}
+		if typeReg != nil {
+			idl.Types = typeReg
+		}
+
+		opts.PkgName = defaultPkgName
+		if opts.PkgName == "" {
+			opts.PkgName = baseName(jsonFile)
+		}
+
+		out, err := generate(idl, gen, outdir, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating code from %s: %s\n", from, err)
+			os.Exit(1)
+		}
+		outputs = append(outputs, out...)
+
+		if skeleton != "" && !check {
+			out, err := generateSkeleton(idl, skeleton, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating skeleton from %s: %s\n", from, err)
+				os.Exit(1)
+			}
+			skeletons = append(skeletons, out...)
+		}
+	}
+
+	if err := checkDuplicates(outputs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 
-	pkgNameToGoCode := idl.GenerateGo(defaultPkgName, baseImport, optionalToPtr)
-	for pkg, code := range pkgNameToGoCode {
-		writeCode(quiet, tostdout, outdir, pkg, code)
+	if check {
+		stale := false
+		for _, out := range outputs {
+			for _, msg := range checkFile(out) {
+				fmt.Println(msg)
+				stale = true
+			}
+		}
+		if stale {
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, out := range outputs {
+		writeOutput(quiet, tostdout, out)
+	}
+	for _, out := range skeletons {
+		if !writeNewOutput(quiet, out) && !quiet {
+			fmt.Printf("Skipping %s: file exists\n", out.path)
+		}
 	}
 }
 
-func writeCode(quiet bool, tostdout bool, outdir string, pkg string, code []byte) {
+// checkDuplicates returns an error if two IDL files generate different files
+// at the same path.  Identical files, e.g. a namespace imported by both, are
+// allowed.
+func checkDuplicates(outputs []output) error {
+	byPath := map[string][]byte{}
+	for _, out := range outputs {
+		data, ok := byPath[out.path]
+		if ok && !bytes.Equal(data, out.data) {
+			return fmt.Errorf("more than one IDL file generates %s", out.path)
+		}
+		byPath[out.path] = out.data
+	}
+	return nil
+}
 
-	if tostdout {
-		fmt.Println(string(code))
-	} else {
+// writeNewOutput writes a generated file unless it already exists, and
+// returns true if it was written
+func writeNewOutput(quiet bool, out output) bool {
+	if _, err := os.Stat(out.path); err == nil {
+		return false
+	}
+	writeOutput(quiet, false, out)
+	return true
+}
+
+// baseName returns the file name without its directory or extension
+func baseName(fname string) string {
+	name := filepath.Base(fname)
+	pos := strings.LastIndex(name, ".")
+	if pos > -1 {
+		name = name[0:pos]
+	}
+	return name
+}
 
-		dir := filepath.Join(outdir, pkg)
-		err := os.MkdirAll(dir, 0755)
+// generate returns the files to write for idl, in a stable order
+func generate(idl *barrister.Idl, gen string, outdir string, opts barrister.GoOptions) ([]output, error) {
+	switch gen {
+	case "jsonschema":
+		schema, err := json.MarshalIndent(idl.JSONSchema(), "", "  ")
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating dir %s: %s\n", dir, err)
-			os.Exit(1)
+			return nil, err
 		}
-		outfile := filepath.Join(dir, fmt.Sprintf("%s.go", pkg))
+		outfile := filepath.Join(outdir, opts.PkgName+".schema.json")
+		return []output{{path: outfile, data: append(schema, '\n')}}, nil
+	case "openrpc":
+		doc, err := json.MarshalIndent(idl.OpenRPC(barrister.OpenRPCInfo{}), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		outfile := filepath.Join(outdir, opts.PkgName+".openrpc.json")
+		return []output{{path: outfile, data: append(doc, '\n')}}, nil
+	case "typescript":
+		ts, err := idl.GenerateTypeScript()
+		if err != nil {
+			return nil, err
+		}
+		outfile := filepath.Join(outdir, opts.PkgName+".ts")
+		return []output{{path: outfile, data: ts}}, nil
+	}
 
-		if !quiet {
-			fmt.Printf("Generating %s as package %s\n", outfile, pkg)
+	pkgNameToGoCode, err := idl.GenerateGoWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := []string{}
+	for pkg := range pkgNameToGoCode {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	outputs := []output{}
+	for _, pkg := range pkgs {
+		outfile := filepath.Join(outdir, pkg, fmt.Sprintf("%s.go", pkg))
+		outputs = append(outputs, output{outfile, pkgNameToGoCode[pkg], pkg, idl.Meta.Checksum})
+	}
+	return outputs, nil
+}
+
+// generateSkeleton returns the server skeleton files for idl, in a stable
+// order
+func generateSkeleton(idl *barrister.Idl, dir string, opts barrister.GoOptions) ([]output, error) {
+	files, err := idl.GenerateSkeleton(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs := []output{}
+	for _, name := range names {
+		outputs = append(outputs, output{path: filepath.Join(dir, name), data: files[name], pkg: "main"})
+	}
+	return outputs, nil
+}
+
+// writeOutput writes a generated file, creating its directory if needed
+func writeOutput(quiet bool, tostdout bool, out output) {
+	if tostdout {
+		if out.pkg != "" {
+			fmt.Println(string(out.data))
+		} else {
+			os.Stdout.Write(out.data)
 		}
+		return
+	}
 
-		err = ioutil.WriteFile(outfile, code, 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing file %s: %s\n", outfile, err)
-			os.Exit(1)
+	dir := filepath.Dir(out.path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating dir %s: %s\n", dir, err)
+		os.Exit(1)
+	}
+
+	if !quiet {
+		if out.pkg != "" {
+			fmt.Printf("Generating %s as package %s\n", out.path, out.pkg)
+		} else {
+			fmt.Printf("Generating %s\n", out.path)
 		}
 	}
+
+	err = ioutil.WriteFile(out.path, out.data, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file %s: %s\n", out.path, err)
+		os.Exit(1)
+	}
 }
 
 func parseIdl(fromstdin bool, jsonFile string) (*barrister.Idl, error) {
@@ -108,5 +358,34 @@ func parseIdl(fromstdin bool, jsonFile string) (*barrister.Idl, error) {
 		return barrister.ParseIdlJson(jsonData)
 	}
 
-	return barrister.ParseIdlJsonFile(jsonFile)
+	return parser.LoadFile(jsonFile)
+}
+
+// parseTypeMappings converts -t flag values of the form: key=import/path.Type
+// into a TypeRegistry.  key is an IDL type or field, e.g. "Person.created".
+// Types declared in the generated package may omit the import path.
+func parseTypeMappings(types []string) (*barrister.TypeRegistry, error) {
+	reg := barrister.NewTypeRegistry()
+	for _, t := range types {
+		pos := strings.Index(t, "=")
+		if pos < 1 || pos == len(t)-1 {
+			return nil, fmt.Errorf("invalid type mapping: %s (expected key=import/path.Type)", t)
+		}
+		key, goType := t[0:pos], t[pos+1:]
+
+		goImport := ""
+		goName := goType
+		dot := strings.LastIndex(goType, ".")
+		if dot > -1 {
+			goImport = goType[0:dot]
+			goName = filepath.Base(goImport) + goType[dot:]
+		}
+
+		m := barrister.TypeMapping{GoName: goName, GoImport: goImport}
+		err := reg.MapIdl(key, m)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
 }
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestCheckDuplicates(t *testing.T) {
+	a := output{path: "gen/a/a.go", data: []byte("package a\n"), pkg: "a"}
+	common := output{path: "gen/common/common.go", data: []byte("package common\n"), pkg: "common"}
+
+	Equals(t, checkDuplicates([]output{a, common, common}), nil)
+
+	other := output{path: "gen/a/a.go", data: []byte("package a\n\nfunc B() {}\n"), pkg: "a"}
+	err := checkDuplicates([]output{a, common, other})
+	NotEquals(t, err, nil)
+	Equals(t, err.Error(), "more than one IDL file generates gen/a/a.go")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coopernurse/barrister-go"
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestGenerateSkeletonNeverOverwrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idl2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	idl := barrister.NewBuilder().
+		Interface("Calc").Function("add", "float", "a float", "b float").
+		MustBuild()
+	outs, err := generateSkeleton(idl, dir, barrister.GoOptions{PkgName: "calc", BaseImport: "example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(outs), 2)
+	Equals(t, outs[0].path, filepath.Join(dir, "calc.go"))
+	Equals(t, outs[1].path, filepath.Join(dir, "main.go"))
+
+	edited := []byte("package main\n\n// edited\n")
+	err = ioutil.WriteFile(outs[0].path, edited, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	False(t, writeNewOutput(true, outs[0]))
+	True(t, writeNewOutput(true, outs[1]))
+
+	data, err := ioutil.ReadFile(outs[0].path)
+	Equals(t, err, nil)
+	DeepEquals(t, data, edited)
+	data, err = ioutil.ReadFile(outs[1].path)
+	Equals(t, err, nil)
+	DeepEquals(t, data, outs[1].data)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/coopernurse/barrister-go"
+	"github.com/coopernurse/barrister-go/parser"
+)
+
+// idlcompat compares two IDL files and reports changes that may break
+// existing clients or servers.
+//
+// Exit status is 0 if all changes are compatible, 1 if any change is
+// breaking, and 2 if either IDL can't be loaded.
+func main() {
+	var asJson bool
+	var quiet bool
+
+	flag.BoolVar(&asJson, "json", false, "Write the report as JSON")
+	flag.BoolVar(&quiet, "q", false, "Only report breaking changes")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: idlcompat [options] old-idl new-idl\n")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	oldIdl := loadIdl(flag.Arg(0))
+	newIdl := loadIdl(flag.Arg(1))
+
+	report := barrister.CompareIdl(oldIdl, newIdl)
+	if quiet {
+		breaking := []barrister.Change{}
+		for _, c := range report.Changes {
+			if c.Breaking {
+				breaking = append(breaking, c)
+			}
+		}
+		report.Changes = breaking
+	}
+
+	if asJson {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %s\n", err)
+			os.Exit(2)
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, c := range report.Changes {
+			fmt.Println(c)
+		}
+		if report.Breaking {
+			fmt.Println("Result: BREAKING")
+		} else {
+			fmt.Println("Result: compatible")
+		}
+	}
+
+	if report.Breaking {
+		os.Exit(1)
+	}
+}
+
+func loadIdl(filename string) *barrister.Idl {
+	idl, err := parser.LoadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading IDL from %s: %s\n", filename, err)
+		os.Exit(2)
+	}
+	return idl
+}
@@ -0,0 +1,145 @@
+package barrister
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// IdlJson returns the IDL JSON document for idl, including the meta element
+func (idl *Idl) IdlJson() ([]byte, error) {
+	return json.MarshalIndent(idl.elems, "", "  ")
+}
+
+// IdlSource returns the IDL as .idl source files.  The keys of the returned
+// map are file names.  Elements that aren't namespaced are written to
+// rootName + ".idl", which imports a "<namespace>.idl" file for each namespace.
+func (idl *Idl) IdlSource(rootName string) map[string][]byte {
+	nsElems := map[string][]IdlJsonElem{}
+	order := []string{}
+	for _, el := range idl.elems {
+		if el.Type == "meta" {
+			continue
+		}
+		ns := ""
+		if el.Type == "struct" || el.Type == "enum" {
+			ns, _ = splitNs(el.Name)
+		}
+		if _, ok := nsElems[ns]; !ok {
+			order = append(order, ns)
+		}
+		nsElems[ns] = append(nsElems[ns], el)
+	}
+
+	files := map[string][]byte{}
+	for _, ns := range order {
+		fname := rootName + ".idl"
+		if ns != "" {
+			fname = ns + ".idl"
+		}
+		files[fname] = idlSourceFile(ns, nsElems[ns])
+	}
+	return files
+}
+
+func idlSourceFile(ns string, elems []IdlJsonElem) []byte {
+	b := &bytes.Buffer{}
+	if ns != "" {
+		b.WriteString(fmt.Sprintf("namespace %s\n\n", ns))
+	}
+
+	// leading comments are written before imports, so the comments
+	// remain first when the file is parsed
+	for len(elems) > 0 && elems[0].Type == "comment" {
+		idlComment(b, 0, elems[0].Value)
+		b.WriteString("\n")
+		elems = elems[1:]
+	}
+
+	imports := findAllImports(ns, elems)
+	for _, imp := range imports {
+		b.WriteString(fmt.Sprintf("import \"%s.idl\"\n", imp))
+	}
+	if len(imports) > 0 {
+		b.WriteString("\n")
+	}
+
+	local := func(name string) string {
+		if ns != "" && strings.HasPrefix(name, ns+".") {
+			return name[len(ns)+1:]
+		}
+		return name
+	}
+	typeSpec := func(f Field) string {
+		s := local(f.Type)
+		if f.IsArray {
+			s = "[]" + s
+		}
+		if f.Optional {
+			s += " [optional]"
+		}
+		return s
+	}
+
+	for x, el := range elems {
+		if x > 0 {
+			b.WriteString("\n")
+		}
+		switch el.Type {
+		case "comment":
+			idlComment(b, 0, el.Value)
+		case "struct":
+			idlComment(b, 0, el.Comment)
+			extends := ""
+			if el.Extends != "" {
+				extends = " extends " + local(el.Extends)
+			}
+			b.WriteString(fmt.Sprintf("struct %s%s {\n", local(el.Name), extends))
+			for _, f := range el.Fields {
+				idlComment(b, 1, f.Comment)
+				b.WriteString(fmt.Sprintf("    %s %s\n", f.Name, typeSpec(f)))
+			}
+			b.WriteString("}\n")
+		case "enum":
+			idlComment(b, 0, el.Comment)
+			b.WriteString(fmt.Sprintf("enum %s {\n", local(el.Name)))
+			for _, v := range el.Values {
+				idlComment(b, 1, v.Comment)
+				b.WriteString(fmt.Sprintf("    %s\n", v.Value))
+			}
+			b.WriteString("}\n")
+		case "interface":
+			idlComment(b, 0, el.Comment)
+			b.WriteString(fmt.Sprintf("interface %s {\n", el.Name))
+			for i, fn := range el.Functions {
+				if i > 0 && fn.Comment != "" {
+					b.WriteString("\n")
+				}
+				idlComment(b, 1, fn.Comment)
+				params := make([]string, len(fn.Params))
+				for p, param := range fn.Params {
+					params[p] = param.Name + " " + typeSpec(param)
+				}
+				b.WriteString(fmt.Sprintf("    %s(%s) %s\n", fn.Name, strings.Join(params, ", "), typeSpec(fn.Returns)))
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// idlComment writes comment as "//" lines indented by level
+func idlComment(b *bytes.Buffer, level int, comment string) {
+	if comment == "" {
+		return
+	}
+	indent := strings.Repeat("    ", level)
+	for _, ln := range strings.Split(comment, "\n") {
+		if ln == "" {
+			b.WriteString(indent + "//\n")
+		} else {
+			b.WriteString(indent + "// " + ln + "\n")
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package barrister
+
+// Interface is a read-only view of an IDL interface
+type Interface struct {
+	Name      string
+	Comment   string
+	Functions []Function
+}
+
+// Enum is a read-only view of an IDL enum
+type Enum struct {
+	Name    string
+	Comment string
+	Values  []EnumValue
+}
+
+// TypeKind classifies an IDL type name
+type TypeKind int
+
+const (
+	// TypeUnknown is returned for names that aren't declared in the IDL
+	TypeUnknown TypeKind = iota
+
+	// TypeBuiltin is one of: string, int, float, bool
+	TypeBuiltin
+
+	TypeStruct
+	TypeEnum
+)
+
+func (k TypeKind) String() string {
+	switch k {
+	case TypeBuiltin:
+		return "builtin"
+	case TypeStruct:
+		return "struct"
+	case TypeEnum:
+		return "enum"
+	}
+	return "unknown"
+}
+
+// The accessors below return copies of the IDL elements in the order they
+// appear in the IDL, so callers may modify the results without affecting idl.
+
+// Elems returns a copy of the elements the Idl was created from
+func (idl *Idl) Elems() []IdlJsonElem {
+	elems := make([]IdlJsonElem, len(idl.elems))
+	for i, el := range idl.elems {
+		el.Fields = copyFields(el.Fields)
+		el.Values = copyValues(el.Values)
+		el.Functions = copyFunctions(el.Functions)
+		elems[i] = el
+	}
+	return elems
+}
+
+// Interfaces returns the interfaces in the IDL
+func (idl *Idl) Interfaces() []Interface {
+	ifaces := []Interface{}
+	for _, el := range idl.elems {
+		if el.Type == "interface" {
+			ifaces = append(ifaces, Interface{el.Name, el.Comment, copyFunctions(el.Functions)})
+		}
+	}
+	return ifaces
+}
+
+// Interface returns the interface with the given name.  ok is false if
+// the interface isn't in the IDL.
+func (idl *Idl) Interface(name string) (iface Interface, ok bool) {
+	for _, i := range idl.Interfaces() {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return Interface{}, false
+}
+
+// Functions returns the functions of the given interface, or nil if the
+// interface isn't in the IDL
+func (idl *Idl) Functions(iface string) []Function {
+	funcs, ok := idl.interfaces[iface]
+	if !ok {
+		return nil
+	}
+	return copyFunctions(funcs)
+}
+
+// Structs returns the structs in the IDL.  Use AllFields to include
+// inherited fields.
+func (idl *Idl) Structs() []Struct {
+	structs := []Struct{}
+	for _, el := range idl.elems {
+		if el.Type == "struct" {
+			s, _ := idl.Struct(el.Name)
+			structs = append(structs, s)
+		}
+	}
+	return structs
+}
+
+// Struct returns the struct with the given name.  ok is false if the
+// struct isn't in the IDL.
+func (idl *Idl) Struct(name string) (s Struct, ok bool) {
+	p, ok := idl.structs[name]
+	if !ok {
+		return Struct{}, false
+	}
+	s = *p
+	s.Fields = copyFields(p.Fields)
+	s.allFields = copyFields(p.allFields)
+	return s, true
+}
+
+// AllFields returns the fields of the struct, starting with the fields
+// inherited from the root of its extends chain
+func (s Struct) AllFields() []Field {
+	return copyFields(s.allFields)
+}
+
+// Enums returns the enums in the IDL
+func (idl *Idl) Enums() []Enum {
+	enums := []Enum{}
+	for _, el := range idl.elems {
+		if el.Type == "enum" {
+			enums = append(enums, Enum{el.Name, el.Comment, copyValues(el.Values)})
+		}
+	}
+	return enums
+}
+
+// Enum returns the enum with the given name.  ok is false if the enum
+// isn't in the IDL.
+func (idl *Idl) Enum(name string) (e Enum, ok bool) {
+	for _, e := range idl.Enums() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Enum{}, false
+}
+
+// Comments returns the text of the top level comment elements in the IDL.
+// Comments attached to interfaces, functions, structs, fields and enums are
+// available on those types.
+func (idl *Idl) Comments() []string {
+	comments := []string{}
+	for _, el := range idl.elems {
+		if el.Type == "comment" {
+			comments = append(comments, el.Value)
+		}
+	}
+	return comments
+}
+
+// TypeKind returns the kind of the given IDL type name.  For fields use
+// f.Type; whether a field is an array is available from f.IsArray.
+func (idl *Idl) TypeKind(typeName string) TypeKind {
+	if isBuiltinType(typeName) {
+		return TypeBuiltin
+	}
+	if _, ok := idl.structs[typeName]; ok {
+		return TypeStruct
+	}
+	if _, ok := idl.enums[typeName]; ok {
+		return TypeEnum
+	}
+	return TypeUnknown
+}
+
+// ResolveStruct returns the struct referenced by the field type.  ok is false
+// if the field type isn't a struct.
+func (idl *Idl) ResolveStruct(f Field) (s Struct, ok bool) {
+	return idl.Struct(f.Type)
+}
+
+// ResolveEnum returns the enum referenced by the field type.  ok is false
+// if the field type isn't an enum.
+func (idl *Idl) ResolveEnum(f Field) (e Enum, ok bool) {
+	return idl.Enum(f.Type)
+}
+
+func copyFields(fields []Field) []Field {
+	if fields == nil {
+		return nil
+	}
+	c := make([]Field, len(fields))
+	copy(c, fields)
+	return c
+}
+
+func copyValues(vals []EnumValue) []EnumValue {
+	if vals == nil {
+		return nil
+	}
+	c := make([]EnumValue, len(vals))
+	copy(c, vals)
+	return c
+}
+
+func copyFunctions(funcs []Function) []Function {
+	if funcs == nil {
+		return nil
+	}
+	c := make([]Function, len(funcs))
+	for i, fn := range funcs {
+		fn.Params = copyFields(fn.Params)
+		c[i] = fn
+	}
+	return c
+}
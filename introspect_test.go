@@ -0,0 +1,82 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestIdlIntrospection(t *testing.T) {
+	idl := MustParseIdlJson(readFile("test/conform.json"))
+
+	ifaces := idl.Interfaces()
+	Equals(t, len(ifaces), 2)
+	Equals(t, ifaces[0].Name, "A")
+	Equals(t, ifaces[1].Name, "B")
+	True(t, len(ifaces[1].Comment) > 0)
+
+	funcs := idl.Functions("A")
+	Equals(t, len(funcs), 7)
+	Equals(t, funcs[0].Name, "add")
+	Equals(t, funcs[6].Name, "putPerson")
+	True(t, idl.Functions("C") == nil)
+
+	structs := idl.Structs()
+	Equals(t, len(structs), 5)
+	Equals(t, structs[0].Name, "Response")
+
+	s, ok := idl.Struct("RepeatResponse")
+	True(t, ok)
+	Equals(t, s.Comment, "testing struct inheritance")
+	Equals(t, len(s.Fields), 2)
+	all := s.AllFields()
+	Equals(t, len(all), 3)
+	Equals(t, all[0].Name, "status")
+	Equals(t, all[2].Name, "items")
+
+	_, ok = idl.Struct("Status")
+	False(t, ok)
+
+	enums := idl.Enums()
+	Equals(t, len(enums), 2)
+	e, ok := idl.Enum("MathOp")
+	True(t, ok)
+	Equals(t, e.Values[1].Comment, "mult comment")
+
+	comments := idl.Comments()
+	Equals(t, len(comments), 1)
+	True(t, len(comments[0]) > 0)
+
+	Equals(t, idl.TypeKind("float"), TypeBuiltin)
+	Equals(t, idl.TypeKind("Person"), TypeStruct)
+	Equals(t, idl.TypeKind("Status"), TypeEnum)
+	Equals(t, idl.TypeKind("A"), TypeUnknown)
+	Equals(t, TypeEnum.String(), "enum")
+
+	repeat := idl.Method("A.repeat")
+	resp, ok := idl.ResolveStruct(repeat.Returns)
+	True(t, ok)
+	Equals(t, resp.Extends, "Response")
+	op, ok := idl.ResolveEnum(idl.Functions("A")[1].Params[1])
+	True(t, ok)
+	Equals(t, op.Name, "MathOp")
+}
+
+func TestIdlIntrospectionReturnsCopies(t *testing.T) {
+	idl := MustParseIdlJson(readFile("test/conform.json"))
+
+	s, _ := idl.Struct("Person")
+	s.Fields[0].Name = "changed"
+	s.AllFields()[0].Name = "changed"
+	idl.Functions("A")[0].Params[0].Name = "changed"
+	idl.Interfaces()[0].Functions[0].Name = "changed"
+	idl.Enums()[0].Values[0].Value = "changed"
+	idl.Elems()[1].Values[0].Value = "changed"
+
+	s, _ = idl.Struct("Person")
+	Equals(t, s.Fields[0].Name, "personId")
+	Equals(t, s.AllFields()[0].Name, "personId")
+	Equals(t, idl.Functions("A")[0].Params[0].Name, "a")
+	Equals(t, idl.Interfaces()[0].Functions[0].Name, "add")
+	Equals(t, idl.Enums()[0].Values[0].Value, "ok")
+}
@@ -0,0 +1,142 @@
+package barrister
+
+// JSONSchemaVersion is the JSON Schema dialect written by Idl.JSONSchema
+const JSONSchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema document or subschema.  Only the keywords
+// needed to describe IDL types are included.
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Ref         string                 `json:"$ref,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Type        interface{}            `json:"type,omitempty"`
+	Enum        []string               `json:"enum,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	PrefixItems []*JSONSchema          `json:"prefixItems,omitempty"`
+	MinItems    *int                   `json:"minItems,omitempty"`
+	MaxItems    *int                   `json:"maxItems,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	AllOf       []*JSONSchema          `json:"allOf,omitempty"`
+	AnyOf       []*JSONSchema          `json:"anyOf,omitempty"`
+	Defs        map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// JSONSchema converts idl to a JSON Schema document.  The document has a
+// definition in "$defs" for:
+//
+// * each struct, named after the struct.  Structs that extend another struct
+// use "allOf" to combine the parent and their own fields.
+//
+// * each enum, named after the enum, as a string with an "enum" list
+//
+// * the params of each function, named "Interface.function.params".  Params
+// are sent by position, so this is an array with one "prefixItems" entry
+// per param.
+//
+// * the result of each function, named "Interface.function.result"
+//
+// Optional fields and results also accept null.
+func (idl *Idl) JSONSchema() *JSONSchema {
+	g := schemaGen{idl, "#/$defs/"}
+	defs := g.typeSchemas()
+
+	for _, iface := range idl.Interfaces() {
+		for _, fn := range iface.Functions {
+			key := iface.Name + "." + fn.Name
+			defs[key+".params"] = g.paramsSchema(fn)
+			defs[key+".result"] = g.fieldSchema(fn.Returns)
+		}
+	}
+
+	return &JSONSchema{Schema: JSONSchemaVersion, Defs: defs}
+}
+
+// schemaGen converts IDL types to JSON Schema.  refPrefix is prepended to
+// struct and enum names in "$ref" values.
+type schemaGen struct {
+	idl       *Idl
+	refPrefix string
+}
+
+// typeSchemas returns a schema for each struct and enum, keyed by name
+func (g schemaGen) typeSchemas() map[string]*JSONSchema {
+	schemas := map[string]*JSONSchema{}
+	for _, s := range g.idl.Structs() {
+		schemas[s.Name] = g.structSchema(s)
+	}
+	for _, e := range g.idl.Enums() {
+		vals := make([]string, len(e.Values))
+		for i, v := range e.Values {
+			vals[i] = v.Value
+		}
+		schemas[e.Name] = &JSONSchema{Title: e.Name, Description: e.Comment, Type: "string", Enum: vals}
+	}
+	return schemas
+}
+
+func (g schemaGen) structSchema(s Struct) *JSONSchema {
+	own := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	for _, f := range s.Fields {
+		own.Properties[f.Name] = g.fieldSchema(f)
+		if !f.Optional {
+			own.Required = append(own.Required, f.Name)
+		}
+	}
+
+	if s.Extends == "" {
+		own.Title = s.Name
+		own.Description = s.Comment
+		return own
+	}
+
+	return &JSONSchema{
+		Title:       s.Name,
+		Description: s.Comment,
+		AllOf:       []*JSONSchema{{Ref: g.refPrefix + s.Extends}, own},
+	}
+}
+
+func (g schemaGen) paramsSchema(fn Function) *JSONSchema {
+	n := len(fn.Params)
+	schema := &JSONSchema{Description: fn.Comment, Type: "array", MinItems: &n, MaxItems: &n}
+	for _, p := range fn.Params {
+		ps := g.fieldSchema(p)
+		ps.Title = p.Name
+		schema.PrefixItems = append(schema.PrefixItems, ps)
+	}
+	return schema
+}
+
+// fieldSchema returns the schema for a field, param or function result
+func (g schemaGen) fieldSchema(f Field) *JSONSchema {
+	var schema *JSONSchema
+	switch f.Type {
+	case "string":
+		schema = &JSONSchema{Type: "string"}
+	case "int":
+		schema = &JSONSchema{Type: "integer"}
+	case "float":
+		schema = &JSONSchema{Type: "number"}
+	case "bool":
+		schema = &JSONSchema{Type: "boolean"}
+	default:
+		schema = &JSONSchema{Ref: g.refPrefix + f.Type}
+	}
+
+	if f.IsArray {
+		schema = &JSONSchema{Type: "array", Items: schema}
+	}
+
+	if f.Optional {
+		if schema.Ref != "" {
+			schema = &JSONSchema{AnyOf: []*JSONSchema{schema, {Type: "null"}}}
+		} else {
+			schema.Type = []string{schema.Type.(string), "null"}
+		}
+	}
+
+	schema.Description = f.Comment
+	return schema
+}
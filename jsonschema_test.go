@@ -0,0 +1,53 @@
+package barrister
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func schemaJson(t *testing.T, s *JSONSchema) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestJSONSchema(t *testing.T) {
+	idl := MustParseIdlJson(readFile("test/conform.json"))
+	schema := idl.JSONSchema()
+
+	Equals(t, schema.Schema, JSONSchemaVersion)
+	Equals(t, len(schema.Defs), 5+2+2*8)
+
+	Equals(t, schemaJson(t, schema.Defs["MathOp"]),
+		`{"title":"MathOp","type":"string","enum":["add","multiply"]}`)
+	Equals(t, schemaJson(t, schema.Defs["Person"]),
+		`{"title":"Person","type":"object","properties":{"email":{"type":["string","null"]},"firstName":{"type":"string"},`+
+			`"lastName":{"type":"string"},"personId":{"type":"string"}},"required":["personId","firstName","lastName"]}`)
+	Equals(t, schemaJson(t, schema.Defs["RepeatResponse"]),
+		`{"title":"RepeatResponse","description":"testing struct inheritance","allOf":[{"$ref":"#/$defs/Response"},`+
+			`{"type":"object","properties":{"count":{"type":"integer"},"items":{"type":"array","items":{"type":"string"}}},"required":["count","items"]}]}`)
+	Equals(t, schemaJson(t, schema.Defs["A.calc.params"]),
+		`{"description":"performs the given operation against \nall the values in nums and returns the result","type":"array",`+
+			`"prefixItems":[{"title":"nums","type":"array","items":{"type":"number"}},{"$ref":"#/$defs/MathOp","title":"operation"}],"minItems":2,"maxItems":2}`)
+	Equals(t, schemaJson(t, schema.Defs["A.repeat_num.result"]),
+		`{"type":"array","items":{"type":"integer"}}`)
+	Equals(t, schemaJson(t, schema.Defs["B.echo.result"]),
+		`{"type":["string","null"]}`)
+	Equals(t, schemaJson(t, schema.Defs["A.say_hi.params"]),
+		`{"description":"returns a result with:\n  hi=\"hi\" and status=\"ok\"","type":"array","minItems":0,"maxItems":0}`)
+}
+
+func TestJSONSchemaOptionalRef(t *testing.T) {
+	idl := NewBuilder().
+		Struct("Child").Field("name", "string").
+		Struct("Parent").Field("child", "Child [optional]").Comment("may be null").
+		MustBuild()
+
+	schema := idl.JSONSchema()
+	Equals(t, schemaJson(t, schema.Defs["Parent"].Properties["child"]),
+		`{"description":"may be null","anyOf":[{"$ref":"#/$defs/Child"},{"type":"null"}]}`)
+}
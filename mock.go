@@ -0,0 +1,123 @@
+package barrister
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ServerTransport sends requests to a Server in-process.  Requests and
+// responses are still encoded by the Server's Serializer, so a Client using
+// a ServerTransport behaves as it would with a remote Server.  It is mostly
+// useful in tests.
+type ServerTransport struct {
+	Server *Server
+
+	// Optional request headers passed to the Server
+	Headers map[string][]string
+}
+
+func (t *ServerTransport) Send(in []byte) ([]byte, error) {
+	headers := Headers{
+		Request:  t.Headers,
+		Response: make(map[string][]string),
+	}
+	if headers.Request == nil {
+		headers.Request = make(map[string][]string)
+	}
+	return t.Server.InvokeBytes(headers, in), nil
+}
+
+// NewServerClient creates a Client that calls s in-process using a
+// ServerTransport
+func NewServerClient(s *Server) Client {
+	return NewRemoteClient(&ServerTransport{Server: s}, false)
+}
+
+// MockCall is a call recorded by a MockRecorder
+type MockCall struct {
+	// IDL method name, e.g. "Calculator.add"
+	Method string
+
+	Params []interface{}
+}
+
+// TestingT is the subset of testing.TB used by MockRecorder
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// MockRecorder records the calls made to the mocks generated by idl2go -mocks.
+// It is safe for concurrent use.
+type MockRecorder struct {
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+// Record appends a call to method with the given params
+func (r *MockRecorder) Record(method string, params ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, MockCall{method, params})
+}
+
+// Calls returns the recorded calls in the order they were made
+func (r *MockRecorder) Calls() []MockCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]MockCall{}, r.calls...)
+}
+
+// CallsTo returns the recorded calls to method in the order they were made
+func (r *MockRecorder) CallsTo(method string) []MockCall {
+	calls := []MockCall{}
+	for _, c := range r.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset discards the recorded calls
+func (r *MockRecorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// AssertCalled reports an error on t unless method was called with params.
+// Params are compared with reflect.DeepEqual.
+func (r *MockRecorder) AssertCalled(t TestingT, method string, params ...interface{}) bool {
+	t.Helper()
+	calls := r.CallsTo(method)
+	for _, c := range calls {
+		if reflect.DeepEqual(c.Params, params) {
+			return true
+		}
+	}
+	msg := fmt.Sprintf("%s was not called with params: %v", method, params)
+	for _, c := range calls {
+		msg += fmt.Sprintf("\n\tcalled with: %v", c.Params)
+	}
+	t.Errorf("%s", msg)
+	return false
+}
+
+// AssertNotCalled reports an error on t if method was called
+func (r *MockRecorder) AssertNotCalled(t TestingT, method string) bool {
+	t.Helper()
+	return r.AssertCallCount(t, method, 0)
+}
+
+// AssertCallCount reports an error on t unless method was called n times
+func (r *MockRecorder) AssertCallCount(t TestingT, method string, n int) bool {
+	t.Helper()
+	count := len(r.CallsTo(method))
+	if count != n {
+		t.Errorf("%s was called %d times, expected %d", method, count, n)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package barrister
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+// fakeT records the errors reported by MockRecorder assertions
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockRecorder(t *testing.T) {
+	r := &MockRecorder{}
+	r.Record("A.add", int64(1), int64(2))
+	r.Record("A.sqrt", 4.0)
+	r.Record("A.add", int64(3), int64(4))
+
+	Equals(t, len(r.Calls()), 3)
+	DeepEquals(t, r.CallsTo("A.add")[1], MockCall{"A.add", []interface{}{int64(3), int64(4)}})
+
+	ft := &fakeT{}
+	True(t, r.AssertCalled(ft, "A.add", int64(1), int64(2)))
+	True(t, r.AssertCallCount(ft, "A.add", 2))
+	True(t, r.AssertNotCalled(ft, "A.calc"))
+	Equals(t, len(ft.errors), 0)
+
+	False(t, r.AssertCalled(ft, "A.sqrt", 9.0))
+	False(t, r.AssertNotCalled(ft, "A.sqrt"))
+	DeepEquals(t, ft.errors, []string{
+		"A.sqrt was not called with params: [9]\n\tcalled with: [4]",
+		"A.sqrt was called 1 times, expected 0",
+	})
+
+	r.Reset()
+	Equals(t, len(r.Calls()), 0)
+}
+
+func TestServerClient(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+	svr.AddHandler("B", BImpl{})
+
+	client := NewServerClient(&svr)
+	res, err := client.Call("B.echo", "hi")
+	Equals(t, err, nil)
+	Equals(t, res, "hi")
+
+	_, err = client.Call("B.echo", 10)
+	Equals(t, err.(*JsonRpcError).Code, -32602)
+}
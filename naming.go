@@ -0,0 +1,175 @@
+package barrister
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy converts an IDL identifier (struct, field, enum value,
+// interface or function name) to an exported Go identifier.
+type NamingStrategy func(idlName string) string
+
+// LegacyNaming upper cases the first letter of the IDL name and leaves
+// the rest unchanged.  For example: "say_hi" becomes "Say_hi"
+//
+// This was the only strategy available in earlier versions of idl2go.  Use
+// it to keep the identifiers of code generated by those versions.
+func LegacyNaming(idlName string) string {
+	return capitalize(idlName)
+}
+
+// MixedCapsNaming converts snake_case and camelCase IDL names to Go MixedCaps,
+// upper casing common initialisms.  For example: "say_hi" becomes "SayHi" and
+// "personId" becomes "PersonID"
+func MixedCapsNaming(idlName string) string {
+	words := splitWords(idlName)
+	if len(words) == 0 {
+		return capitalize(idlName)
+	}
+
+	s := ""
+	for _, w := range words {
+		upper := strings.ToUpper(w)
+		if commonInitialisms[upper] {
+			s += upper
+		} else {
+			s += capitalize(w)
+		}
+	}
+	return s
+}
+
+// checkGoNames returns an error if naming converts two different IDL names
+// to the same Go identifier where both are declared in the same scope: the
+// structs, enums and interfaces of a namespace, the fields of a struct
+// (including inherited fields), the functions of an interface, or the
+// values of an enum.
+func (idl *Idl) checkGoNames(naming NamingStrategy) error {
+	types := map[string]string{}
+	for _, el := range idl.elems {
+		if el.Type != "struct" && el.Type != "enum" && el.Type != "interface" {
+			continue
+		}
+		ns, name := splitNs(el.Name)
+		scope := "IDL"
+		if ns != "" {
+			scope = "namespace " + ns
+		}
+		if err := checkGoName(types, ns+"."+naming(name), el.Name, scope); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range idl.Structs() {
+		fields := map[string]string{}
+		for _, f := range s.allFields {
+			if err := checkGoName(fields, naming(f.Name), f.Name, "struct "+s.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range idl.Enums() {
+		vals := map[string]string{}
+		for _, v := range e.Values {
+			if err := checkGoName(vals, naming(v.Value), v.Value, "enum "+e.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, iface := range idl.Interfaces() {
+		funcs := map[string]string{}
+		for _, fn := range iface.Functions {
+			if err := checkGoName(funcs, naming(fn.Name), fn.Name, "interface "+iface.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkGoName adds goName to seen, and returns an error if another IDL
+// name in scope already has that Go name
+func checkGoName(seen map[string]string, goName string, idlName string, scope string) error {
+	if other, ok := seen[goName]; ok && other != idlName {
+		_, goIdent := splitNs(goName)
+		return fmt.Errorf("barrister: %s: IDL names %q and %q are both generated as Go name %s",
+			scope, other, idlName, goIdent)
+	}
+	seen[goName] = idlName
+	return nil
+}
+
+// handlerNamings are the strategies tried, in order, when resolving
+// the Go method for an IDL function on a Server handler
+var handlerNamings = []NamingStrategy{MixedCapsNaming, LegacyNaming}
+
+// splitWords splits s on underscores and at lower/upper case boundaries.
+// A run of upper case letters is kept together as one word, so
+// "userURLPath" returns: "user", "URL", "Path"
+func splitWords(s string) []string {
+	words := []string{}
+	runes := []rune(s)
+	start := 0
+	for i := 0; i <= len(runes); i++ {
+		if i == len(runes) || runes[i] == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+
+		if i > start && unicode.IsUpper(runes[i]) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+	}
+	return words
+}
+
+// commonInitialisms is the list of initialisms used by golint
+var commonInitialisms = map[string]bool{
+	"ACL":   true,
+	"API":   true,
+	"ASCII": true,
+	"CPU":   true,
+	"CSS":   true,
+	"DNS":   true,
+	"EOF":   true,
+	"GUID":  true,
+	"HTML":  true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"IP":    true,
+	"JSON":  true,
+	"LHS":   true,
+	"QPS":   true,
+	"RAM":   true,
+	"RHS":   true,
+	"RPC":   true,
+	"SLA":   true,
+	"SMTP":  true,
+	"SQL":   true,
+	"SSH":   true,
+	"TCP":   true,
+	"TLS":   true,
+	"TTL":   true,
+	"UDP":   true,
+	"UI":    true,
+	"UID":   true,
+	"UUID":  true,
+	"URI":   true,
+	"URL":   true,
+	"UTF8":  true,
+	"VM":    true,
+	"XML":   true,
+	"XMPP":  true,
+	"XSRF":  true,
+	"XSS":   true,
+}
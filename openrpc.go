@@ -0,0 +1,105 @@
+package barrister
+
+import (
+	"strings"
+)
+
+// OpenRPCVersion is the OpenRPC specification version written by Idl.OpenRPC
+const OpenRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC service description.  See: https://spec.open-rpc.org/
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []OpenRPCMethod   `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo is the metadata about the API
+type OpenRPCInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenRPCMethod describes a single IDL function
+type OpenRPCMethod struct {
+	Name           string                     `json:"name"`
+	Description    string                     `json:"description,omitempty"`
+	Params         []OpenRPCContentDescriptor `json:"params"`
+	Result         OpenRPCContentDescriptor   `json:"result"`
+	ParamStructure string                     `json:"paramStructure"`
+}
+
+// OpenRPCContentDescriptor describes a param or result
+type OpenRPCContentDescriptor struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *JSONSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of the IDL structs and enums
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// OpenRPC converts idl to an OpenRPC document.  Methods are named
+// "Interface.function", as they are when called via JSON-RPC, and params
+// are by position.  Structs and enums are written to components.schemas.
+// IDL comments become descriptions.
+//
+// If info.Title is empty the interface names are used.  If info.Description
+// is empty the top level IDL comments are used.  If info.Version is empty
+// the IDL checksum is used, or "0.0.0" if the IDL has no checksum.
+func (idl *Idl) OpenRPC(info OpenRPCInfo) *OpenRPCDocument {
+	g := schemaGen{idl, "#/components/schemas/"}
+
+	doc := &OpenRPCDocument{
+		OpenRPC:    OpenRPCVersion,
+		Info:       info,
+		Methods:    []OpenRPCMethod{},
+		Components: OpenRPCComponents{Schemas: g.typeSchemas()},
+	}
+
+	names := []string{}
+	for _, iface := range idl.Interfaces() {
+		names = append(names, iface.Name)
+		for _, fn := range iface.Functions {
+			m := OpenRPCMethod{
+				Name:           iface.Name + "." + fn.Name,
+				Description:    fn.Comment,
+				Params:         []OpenRPCContentDescriptor{},
+				ParamStructure: "by-position",
+			}
+			for _, p := range fn.Params {
+				m.Params = append(m.Params, OpenRPCContentDescriptor{
+					Name:        p.Name,
+					Description: p.Comment,
+					Required:    true,
+					Schema:      g.fieldSchema(p),
+				})
+			}
+			m.Result = OpenRPCContentDescriptor{
+				Name:     "result",
+				Required: !fn.Returns.Optional,
+				Schema:   g.fieldSchema(fn.Returns),
+			}
+			doc.Methods = append(doc.Methods, m)
+		}
+	}
+
+	if doc.Info.Title == "" {
+		doc.Info.Title = strings.Join(names, ", ")
+	}
+	if doc.Info.Description == "" {
+		doc.Info.Description = strings.Join(idl.Comments(), "\n\n")
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = idl.Meta.Checksum
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "0.0.0"
+	}
+	return doc
+}
@@ -0,0 +1,65 @@
+package barrister
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestOpenRPC(t *testing.T) {
+	idl := MustParseIdlJson(readFile("test/conform.json"))
+	doc := idl.OpenRPC(OpenRPCInfo{Title: "Conform"})
+
+	Equals(t, doc.OpenRPC, OpenRPCVersion)
+	Equals(t, doc.Info.Title, "Conform")
+	Equals(t, doc.Info.Version, idl.Meta.Checksum)
+	Equals(t, doc.Info.Description, idl.Comments()[0])
+	Equals(t, len(doc.Methods), 8)
+	Equals(t, len(doc.Components.Schemas), 7)
+
+	calc := doc.Methods[1]
+	Equals(t, calc.Name, "A.calc")
+	Equals(t, calc.ParamStructure, "by-position")
+	Equals(t, len(calc.Params), 2)
+	Equals(t, calc.Params[1].Name, "operation")
+	Equals(t, calc.Params[1].Required, true)
+	Equals(t, calc.Params[1].Schema.Ref, "#/components/schemas/MathOp")
+	Equals(t, calc.Result.Required, true)
+
+	echo := doc.Methods[7]
+	Equals(t, echo.Name, "B.echo")
+	Equals(t, echo.Result.Required, false)
+
+	resp := doc.Components.Schemas["RepeatResponse"]
+	Equals(t, resp.AllOf[0].Ref, "#/components/schemas/Response")
+
+	defaults := idl.OpenRPC(OpenRPCInfo{})
+	Equals(t, defaults.Info.Title, "A, B")
+}
+
+func TestServerDiscover(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, true)
+	headers := newHeaders()
+
+	req := []byte(`{"jsonrpc":"2.0","id":"1","method":"rpc.discover"}`)
+
+	resp := JsonRpcResponse{}
+	err := json.Unmarshal(svr.InvokeBytes(headers, req), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, resp.Error.Code, -32601)
+
+	svr.EnableDiscover(OpenRPCInfo{Title: "Conform", Version: "1.0"})
+	doc := OpenRPCDocument{}
+	resp = JsonRpcResponse{Result: &doc}
+	err = json.Unmarshal(svr.InvokeBytes(headers, req), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	True(t, resp.Error == nil)
+	Equals(t, doc.Info.Version, "1.0")
+	Equals(t, doc.Methods[0].Name, "A.add")
+}
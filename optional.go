@@ -0,0 +1,94 @@
+package barrister
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional holds the value of an optional IDL field or function result, and
+// whether it is set.  Unlike a pointer or a field with omitempty, it can
+// hold any value, including 0, false and "", so optional scalars
+// round-trip faithfully.  It is generated by idl2go -generic-optionals.
+//
+// An Optional is in one of three states: set to a value, null, or absent.
+// The zero Optional is absent.  JSON null unmarshals to a null Optional, and
+// both null and absent Optionals are marshaled as JSON null.  Struct fields
+// should use the omitzero option so that absent fields are omitted, while
+// null fields are sent as null:
+//
+//	Age barrister.Optional[int64] `json:"age,omitzero"`
+type Optional[T any] struct {
+	Value T
+
+	// Set is true if the Optional holds Value
+	Set bool
+
+	// Null is true if the Optional was explicitly null.  Set and Null are
+	// never both true.
+	Null bool
+}
+
+// Some returns an Optional that is set to v
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{Value: v, Set: true}
+}
+
+// Null returns an Optional that is explicitly null
+func Null[T any]() Optional[T] {
+	return Optional[T]{Null: true}
+}
+
+// Get returns the value, and true if it is set
+func (o Optional[T]) Get() (T, bool) {
+	return o.Value, o.Set
+}
+
+// OrElse returns the value if it is set, or def if not
+func (o Optional[T]) OrElse(def T) T {
+	if o.Set {
+		return o.Value
+	}
+	return def
+}
+
+// IsZero returns true if o is absent, i.e. neither set nor null.  It is
+// used by the omitzero JSON option.
+func (o Optional[T]) IsZero() bool {
+	return !o.Set && !o.Null
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.Set {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.Value)
+}
+
+func (o *Optional[T]) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*o = Optional[T]{Null: true}
+		return nil
+	}
+	if err := json.Unmarshal(b, &o.Value); err != nil {
+		return err
+	}
+	o.Set = true
+	return nil
+}
+
+func (o Optional[T]) isOptional() {}
+
+// optional is implemented by all Optional types, so they can be recognized
+// by reflection
+type optional interface {
+	isOptional()
+}
+
+var optionalType = reflect.TypeOf((*optional)(nil)).Elem()
+
+// isOptionalType returns true if t is an Optional type.  Its Value, Set
+// and Null fields are fields 0, 1 and 2.
+func isOptionalType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.Implements(optionalType)
+}
@@ -0,0 +1,159 @@
+package barrister
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+type optPerson struct {
+	PersonId  string           `json:"personId"`
+	FirstName string           `json:"firstName"`
+	LastName  string           `json:"lastName"`
+	Email     Optional[string] `json:"email,omitzero"`
+}
+
+type optEvent struct {
+	Name string              `json:"name"`
+	At   Optional[time.Time] `json:"at,omitzero"`
+}
+
+func TestOptionalJSON(t *testing.T) {
+	b, err := json.Marshal(optPerson{PersonId: "1", Email: Some("")})
+	Equals(t, err, nil)
+	Equals(t, string(b), `{"personId":"1","firstName":"","lastName":"","email":""}`)
+
+	b, err = json.Marshal(optPerson{PersonId: "1"})
+	Equals(t, err, nil)
+	Equals(t, string(b), `{"personId":"1","firstName":"","lastName":""}`)
+
+	b, err = json.Marshal(optPerson{PersonId: "1", Email: Null[string]()})
+	Equals(t, err, nil)
+	Equals(t, string(b), `{"personId":"1","firstName":"","lastName":"","email":null}`)
+
+	b, err = json.Marshal([]Optional[int64]{Some(int64(0)), Null[int64](), {}})
+	Equals(t, err, nil)
+	Equals(t, string(b), `[0,null,null]`)
+
+	var p optPerson
+	err = json.Unmarshal([]byte(`{"email":""}`), &p)
+	Equals(t, err, nil)
+	Equals(t, p.Email, Some(""))
+
+	p = optPerson{Email: Some("a")}
+	err = json.Unmarshal([]byte(`{"email":null}`), &p)
+	Equals(t, err, nil)
+	Equals(t, p.Email, Null[string]())
+	Equals(t, p.Email.OrElse("none"), "none")
+
+	p = optPerson{}
+	err = json.Unmarshal([]byte(`{"personId":"1"}`), &p)
+	Equals(t, err, nil)
+	Equals(t, p.Email, Optional[string]{})
+
+	err = json.Unmarshal([]byte(`{"email":10}`), &p)
+	NotEquals(t, err, nil)
+}
+
+func TestConvertOptional(t *testing.T) {
+	idl := parseTestIdl()
+	person := &Field{Type: "Person"}
+
+	val, err := Convert(idl, person, reflect.TypeOf(optPerson{}), map[string]interface{}{
+		"personId": "1", "firstName": "a", "lastName": "b", "email": ""}, "")
+	Equals(t, err, nil)
+	Equals(t, val.(optPerson).Email, Some(""))
+
+	for _, email := range []interface{}{nil, "missing"} {
+		m := map[string]interface{}{"personId": "1", "firstName": "a", "lastName": "b"}
+		if email == nil {
+			m["email"] = nil
+		}
+		val, err = Convert(idl, person, reflect.TypeOf(optPerson{}), m, "")
+		Equals(t, err, nil)
+		False(t, val.(optPerson).Email.Set)
+		Equals(t, val.(optPerson).Email.Null, email == nil)
+	}
+
+	_, err = Convert(idl, person, reflect.TypeOf(optPerson{}), map[string]interface{}{
+		"personId": "1", "firstName": "a", "lastName": "b", "email": 10.0}, "")
+	NotEquals(t, err, nil)
+
+	// optional result
+	echo := idl.Method("B.echo").Returns
+	val, err = Convert(idl, &echo, reflect.TypeOf(Optional[string]{}), "", "")
+	Equals(t, err, nil)
+	Equals(t, val, Some(""))
+
+	val, err = Convert(idl, &echo, reflect.TypeOf(Optional[string]{}), nil, "")
+	Equals(t, err, nil)
+	Equals(t, val, Null[string]())
+
+	// null is not allowed for required values, even if the Go type is an
+	// Optional
+	_, err = Convert(idl, &Field{Type: "int"}, reflect.TypeOf(Optional[int64]{}), nil, "")
+	NotEquals(t, err, nil)
+}
+
+func TestConvertOptionalTypeMapping(t *testing.T) {
+	idl := createTypeMapIdl()
+	idl.elems[0].Fields[1].Optional = true
+	idl.structs["Event"].Fields[1].Optional = true
+	idl.computeAllStructFields()
+
+	val, err := Convert(idl, &Field{Type: "Event"}, reflect.TypeOf(optEvent{}),
+		map[string]interface{}{"name": "a", "at": "2014-03-01T10:00:00Z"}, "")
+	Equals(t, err, nil)
+	Equals(t, val.(optEvent).At, Some(time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)))
+
+	enc, err := idl.EncodeValue([]optEvent{val.(optEvent), {Name: "b"}, {Name: "c", At: Null[time.Time]()}})
+	Equals(t, err, nil)
+	DeepEquals(t, enc, []interface{}{
+		map[string]interface{}{"name": "a", "at": "2014-03-01T10:00:00Z"},
+		map[string]interface{}{"name": "b"},
+		map[string]interface{}{"name": "c", "at": nil},
+	})
+}
+
+type optEchoImpl struct{}
+
+func (optEchoImpl) Echo(s string) (Optional[string], error) {
+	if s == "return-null" {
+		return Optional[string]{}, nil
+	}
+	return Some(s), nil
+}
+
+type optEchoImpl_Required struct{}
+
+func (optEchoImpl_Required) Echo(s Optional[string]) (string, error) {
+	return s.Value, nil
+}
+
+func TestServerOptionalResult(t *testing.T) {
+	idl := parseTestIdl()
+	svr := NewJSONServer(idl, false)
+	svr.AddHandler("B", optEchoImpl{})
+	client := NewServerClient(&svr)
+
+	for _, s := range []string{"", "return-null"} {
+		res, err := Call[Optional[string]](context.Background(), client, idl, "B.echo", s)
+		Equals(t, err, nil)
+		Equals(t, res.Set, s == "")
+	}
+
+	fx := func() {
+		defer func() {
+			r := recover()
+			True(t, strings.Contains(r.(string), "B.Echo param[0] has invalid type"))
+		}()
+		svr.AddHandler("B", optEchoImpl_Required{})
+		t.Errorf("AddHandler allowed an Optional for a required param")
+	}
+	fx()
+}
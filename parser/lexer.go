@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tEOF tokenKind = iota
+	tIdent
+	tString
+	tComment
+	tPunct
+)
+
+// token is a single lexical element of an IDL file.  Consecutive comment
+// lines are merged into a single tComment token.
+type token struct {
+	kind tokenKind
+	text string
+
+	// line and column of the first character, and the last
+	// line of the token (only differs for comment blocks)
+	line    int
+	col     int
+	endLine int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tEOF:
+		return "end of file"
+	case tString:
+		return fmt.Sprintf("\"%s\"", t.text)
+	case tComment:
+		return "comment"
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// lex splits src into tokens
+func lex(filename string, src string) ([]token, error) {
+	tokens := []token{}
+	lines := strings.Split(strings.Replace(src, "\r\n", "\n", -1), "\n")
+
+	for x, ln := range lines {
+		lineNum := x + 1
+		runes := []rune(ln)
+		i := 0
+		for i < len(runes) {
+			r := runes[i]
+			col := i + 1
+			switch {
+			case unicode.IsSpace(r):
+				i++
+			case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+				text := commentText(string(runes[i+2:]))
+				last := len(tokens) - 1
+				if last >= 0 && tokens[last].kind == tComment && tokens[last].endLine == lineNum-1 {
+					tokens[last].text += "\n" + text
+					tokens[last].endLine = lineNum
+				} else {
+					tokens = append(tokens, token{tComment, text, lineNum, col, lineNum})
+				}
+				i = len(runes)
+			case r == '"':
+				end := i + 1
+				for end < len(runes) && runes[end] != '"' {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("%s:%d:%d: unterminated string", filename, lineNum, col)
+				}
+				tokens = append(tokens, token{tString, string(runes[i+1 : end]), lineNum, col, lineNum})
+				i = end + 1
+			case strings.ContainsRune("{}()[],", r):
+				tokens = append(tokens, token{tPunct, string(r), lineNum, col, lineNum})
+				i++
+			case isIdentRune(r):
+				end := i
+				for end < len(runes) && isIdentRune(runes[end]) {
+					end++
+				}
+				tokens = append(tokens, token{tIdent, string(runes[i:end]), lineNum, col, lineNum})
+				i = end
+			default:
+				return nil, fmt.Errorf("%s:%d:%d: unexpected character: '%c'", filename, lineNum, col, r)
+			}
+		}
+	}
+
+	tokens = append(tokens, token{tEOF, "", len(lines), 1, len(lines)})
+	return tokens, nil
+}
+
+// commentText strips the single space that conventionally follows "//"
+func commentText(s string) string {
+	if strings.HasPrefix(s, " ") {
+		return s[1:]
+	}
+	return s
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,508 @@
+// Package parser parses Barrister IDL source files into the IDL JSON
+// element representation used by the barrister package.  It removes the
+// need to run the Python barrister translator before idl2go.
+//
+// Supported syntax:
+//
+//	// comments
+//	namespace inc
+//	import "common.idl"
+//
+//	struct Person extends Base {
+//	    name   string
+//	    emails []string
+//	    age    int [optional]
+//	}
+//
+//	enum Status {
+//	    ok
+//	    err
+//	}
+//
+//	interface UserService {
+//	    get(id string) Person [optional]
+//	}
+//
+// Comments directly above an element, field, enum value or function are
+// attached to it.  Top level comments followed by a blank line become
+// "comment" elements.
+//
+// Elements declared in a file with a namespace are prefixed with the
+// namespace (e.g. "inc.Status"), as are references to them within that file.
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// ParseFile parses the IDL file and any files it imports.  The returned
+// slice ends with a "meta" element that contains the IDL checksum from
+// barrister.ComputeChecksum, which differs from the checksum the Python
+// translator computes.  The meta element's DateGenerated is zero, so code
+// generated from the same IDL source is always identical.
+//
+// If the IDL parses but is semantically invalid, the barrister.ValidationErrors
+// from barrister.ValidateIdl is returned.
+func ParseFile(filename string) ([]barrister.IdlJsonElem, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filename, src)
+}
+
+// Parse parses IDL source.  filename is used in error messages and to
+// resolve imports, which are relative to the directory of filename.
+func Parse(filename string, src []byte) ([]barrister.IdlJsonElem, error) {
+	r := &resolver{inProgress: map[string]bool{}, done: map[string]bool{}}
+	elems, err := r.parse(filename, src, true)
+	if err != nil {
+		return nil, err
+	}
+	err = barrister.ValidateIdl(elems)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := barrister.NewMetaElem(elems)
+	meta.DateGenerated = 0
+	return append(elems, meta), nil
+}
+
+// MustParseFile calls ParseFile and panics if an error is returned
+func MustParseFile(filename string) []barrister.IdlJsonElem {
+	elems, err := ParseFile(filename)
+	if err != nil {
+		panic(err)
+	}
+	return elems
+}
+
+// LoadFile returns the Idl for filename.  Files ending in ".idl" are parsed
+// with ParseFile, and all other files are loaded as IDL JSON.
+func LoadFile(filename string) (*barrister.Idl, error) {
+	if !strings.HasSuffix(filename, ".idl") {
+		return barrister.ParseIdlJsonFile(filename)
+	}
+
+	elems, err := ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return barrister.NewIdl(elems), nil
+}
+
+// resolver parses a file and its imports, ensuring each file
+// is only included once
+type resolver struct {
+	inProgress map[string]bool
+	done       map[string]bool
+}
+
+func (r *resolver) parse(filename string, src []byte, root bool) ([]barrister.IdlJsonElem, error) {
+	key, err := filepath.Abs(filename)
+	if err != nil {
+		key = filename
+	}
+	if r.inProgress[key] {
+		return nil, fmt.Errorf("%s: import cycle", filename)
+	}
+	r.inProgress[key] = true
+	defer delete(r.inProgress, key)
+
+	tokens, err := lex(filename, string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &fileParser{filename: filename, tokens: tokens}
+	err = p.parseFile()
+	if err != nil {
+		return nil, err
+	}
+	p.qualify()
+
+	elems := []barrister.IdlJsonElem{}
+	for _, item := range p.items {
+		if item.importPath == "" {
+			if root || item.elem.Type != "comment" {
+				elems = append(elems, item.elem)
+			}
+			continue
+		}
+
+		path := filepath.Join(filepath.Dir(filename), item.importPath)
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		if r.done[abs] {
+			continue
+		}
+
+		imported, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: unable to import %s: %s", filename, item.line, item.importPath, err)
+		}
+		importedElems, err := r.parse(path, imported, false)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, importedElems...)
+		r.done[abs] = true
+	}
+
+	return elems, nil
+}
+
+// item is a top level element or an import statement
+type item struct {
+	elem       barrister.IdlJsonElem
+	importPath string
+	line       int
+}
+
+type fileParser struct {
+	filename  string
+	tokens    []token
+	pos       int
+	namespace string
+	items     []item
+}
+
+func (p *fileParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *fileParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *fileParser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d:%d: %s", p.filename, t.line, t.col, fmt.Sprintf(format, args...))
+}
+
+// expect consumes the next token and returns an error if it is not
+// of the given kind (and text, if text is not empty)
+func (p *fileParser) expect(kind tokenKind, text string, desc string) (token, error) {
+	t := p.next()
+	if t.kind != kind || (text != "" && t.text != text) {
+		return t, p.errorf(t, "expected %s, got %s", desc, t)
+	}
+	return t, nil
+}
+
+// comments consumes any comment tokens and returns the comment attached to the
+// following token.  Comments separated from the following token by a blank line
+// are returned as detached.
+func (p *fileParser) comments() (attached string, detached []string) {
+	for p.peek().kind == tComment {
+		c := p.next()
+		if p.peek().kind != tComment && p.peek().line == c.endLine+1 && p.peek().kind != tEOF {
+			attached = trimBlankLines(c.text)
+		} else {
+			detached = append(detached, trimBlankLines(c.text))
+		}
+	}
+	return attached, detached
+}
+
+func (p *fileParser) parseFile() error {
+	for {
+		comment, detached := p.comments()
+		for _, c := range detached {
+			p.items = append(p.items, item{elem: barrister.IdlJsonElem{Type: "comment", Value: c}})
+		}
+
+		t := p.next()
+		if t.kind == tEOF {
+			return nil
+		}
+		if t.kind != tIdent {
+			return p.errorf(t, "expected struct, enum, interface, namespace or import, got %s", t)
+		}
+
+		var err error
+		switch t.text {
+		case "namespace":
+			err = p.parseNamespace(t)
+		case "import":
+			err = p.parseImport(t)
+		case "struct":
+			err = p.parseStruct(comment)
+		case "enum":
+			err = p.parseEnum(comment)
+		case "interface":
+			err = p.parseInterface(comment)
+		default:
+			err = p.errorf(t, "expected struct, enum, interface, namespace or import, got %s", t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *fileParser) parseNamespace(t token) error {
+	if p.namespace != "" {
+		return p.errorf(t, "namespace already declared: %s", p.namespace)
+	}
+	ns, err := p.expect(tIdent, "", "namespace name")
+	if err != nil {
+		return err
+	}
+	p.namespace = ns.text
+	return nil
+}
+
+func (p *fileParser) parseImport(t token) error {
+	path, err := p.expect(tString, "", "quoted import path")
+	if err != nil {
+		return err
+	}
+	p.items = append(p.items, item{importPath: path.text, line: t.line})
+	return nil
+}
+
+func (p *fileParser) parseStruct(comment string) error {
+	name, err := p.expect(tIdent, "", "struct name")
+	if err != nil {
+		return err
+	}
+
+	elem := barrister.IdlJsonElem{Type: "struct", Name: name.text, Comment: comment, Fields: []barrister.Field{}}
+	if p.peek().kind == tIdent && p.peek().text == "extends" {
+		p.next()
+		parent, err := p.expect(tIdent, "", "struct name after extends")
+		if err != nil {
+			return err
+		}
+		elem.Extends = parent.text
+	}
+
+	_, err = p.expect(tPunct, "{", "'{'")
+	if err != nil {
+		return err
+	}
+
+	for {
+		comment, _ := p.comments()
+		if p.peek().kind == tPunct && p.peek().text == "}" {
+			p.next()
+			break
+		}
+
+		fname, err := p.expect(tIdent, "", "field name or '}'")
+		if err != nil {
+			return err
+		}
+		f, err := p.parseType(fname.text)
+		if err != nil {
+			return err
+		}
+		f.Optional, err = p.parseOptional()
+		if err != nil {
+			return err
+		}
+		f.Comment = comment
+		elem.Fields = append(elem.Fields, f)
+	}
+
+	p.items = append(p.items, item{elem: elem, line: name.line})
+	return nil
+}
+
+func (p *fileParser) parseEnum(comment string) error {
+	name, err := p.expect(tIdent, "", "enum name")
+	if err != nil {
+		return err
+	}
+
+	elem := barrister.IdlJsonElem{Type: "enum", Name: name.text, Comment: comment, Values: []barrister.EnumValue{}}
+	_, err = p.expect(tPunct, "{", "'{'")
+	if err != nil {
+		return err
+	}
+
+	for {
+		comment, _ := p.comments()
+		if p.peek().kind == tPunct && p.peek().text == "}" {
+			p.next()
+			break
+		}
+
+		val, err := p.expect(tIdent, "", "enum value or '}'")
+		if err != nil {
+			return err
+		}
+		elem.Values = append(elem.Values, barrister.EnumValue{Value: val.text, Comment: comment})
+	}
+
+	p.items = append(p.items, item{elem: elem, line: name.line})
+	return nil
+}
+
+func (p *fileParser) parseInterface(comment string) error {
+	name, err := p.expect(tIdent, "", "interface name")
+	if err != nil {
+		return err
+	}
+
+	elem := barrister.IdlJsonElem{Type: "interface", Name: name.text, Comment: comment, Functions: []barrister.Function{}}
+	_, err = p.expect(tPunct, "{", "'{'")
+	if err != nil {
+		return err
+	}
+
+	for {
+		comment, _ := p.comments()
+		if p.peek().kind == tPunct && p.peek().text == "}" {
+			p.next()
+			break
+		}
+
+		fname, err := p.expect(tIdent, "", "function name or '}'")
+		if err != nil {
+			return err
+		}
+		fn := barrister.Function{Name: fname.text, Comment: comment, Params: []barrister.Field{}}
+
+		_, err = p.expect(tPunct, "(", "'('")
+		if err != nil {
+			return err
+		}
+		for !(p.peek().kind == tPunct && p.peek().text == ")") {
+			if len(fn.Params) > 0 {
+				_, err = p.expect(tPunct, ",", "',' or ')'")
+				if err != nil {
+					return err
+				}
+			}
+			pname, err := p.expect(tIdent, "", "param name")
+			if err != nil {
+				return err
+			}
+			param, err := p.parseType(pname.text)
+			if err != nil {
+				return err
+			}
+			fn.Params = append(fn.Params, param)
+		}
+		p.next()
+
+		fn.Returns, err = p.parseType("")
+		if err != nil {
+			return err
+		}
+		fn.Returns.Optional, err = p.parseOptional()
+		if err != nil {
+			return err
+		}
+		elem.Functions = append(elem.Functions, fn)
+	}
+
+	p.items = append(p.items, item{elem: elem, line: name.line})
+	return nil
+}
+
+// parseType parses a type with an optional "[]" array prefix
+func (p *fileParser) parseType(name string) (barrister.Field, error) {
+	f := barrister.Field{Name: name}
+	if p.peek().kind == tPunct && p.peek().text == "[" {
+		p.next()
+		_, err := p.expect(tPunct, "]", "']'")
+		if err != nil {
+			return f, err
+		}
+		f.IsArray = true
+	}
+
+	t, err := p.expect(tIdent, "", "type")
+	if err != nil {
+		return f, err
+	}
+	f.Type = t.text
+	return f, nil
+}
+
+// parseOptional parses an optional "[optional]" suffix
+func (p *fileParser) parseOptional() (bool, error) {
+	if p.peek().kind != tPunct || p.peek().text != "[" {
+		return false, nil
+	}
+	p.next()
+	_, err := p.expect(tIdent, "optional", "'optional'")
+	if err != nil {
+		return false, err
+	}
+	_, err = p.expect(tPunct, "]", "']'")
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// qualify prefixes structs and enums declared in a namespaced file, and
+// references to them, with the namespace
+func (p *fileParser) qualify() {
+	if p.namespace == "" {
+		return
+	}
+
+	declared := map[string]bool{}
+	for _, it := range p.items {
+		if it.elem.Type == "struct" || it.elem.Type == "enum" {
+			declared[it.elem.Name] = true
+		}
+	}
+
+	qualify := func(name string) string {
+		if declared[name] {
+			return p.namespace + "." + name
+		}
+		return name
+	}
+	qualifyFields := func(fields []barrister.Field) {
+		for i := range fields {
+			fields[i].Type = qualify(fields[i].Type)
+		}
+	}
+
+	for i := range p.items {
+		el := &p.items[i].elem
+		switch el.Type {
+		case "struct":
+			el.Name = qualify(el.Name)
+			el.Extends = qualify(el.Extends)
+			qualifyFields(el.Fields)
+		case "enum":
+			el.Name = qualify(el.Name)
+		case "interface":
+			for j := range el.Functions {
+				qualifyFields(el.Functions[j].Params)
+				el.Functions[j].Returns.Type = qualify(el.Functions[j].Returns.Type)
+			}
+		}
+	}
+}
+
+// trimBlankLines removes leading and trailing blank lines from a comment
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
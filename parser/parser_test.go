@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coopernurse/barrister-go"
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func withoutMeta(elems []barrister.IdlJsonElem) []barrister.IdlJsonElem {
+	out := []barrister.IdlJsonElem{}
+	for _, el := range elems {
+		if el.Type != "meta" {
+			out = append(out, el)
+		}
+	}
+	return out
+}
+
+func TestParseFileMatchesTranslator(t *testing.T) {
+	elems, err := ParseFile("testdata/conform.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []barrister.IdlJsonElem{}
+	err = json.Unmarshal(readFile(t, "../conform/conform.json"), &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	DeepEquals(t, withoutMeta(elems), withoutMeta(expected))
+
+	meta := elems[len(elems)-1]
+	Equals(t, meta.Type, "meta")
+	Equals(t, meta.BarristerVersion, barrister.BarristerVersion)
+	Equals(t, meta.Checksum, barrister.ComputeChecksum(elems))
+	Equals(t, meta.DateGenerated, int64(0))
+
+	// the checksum is Go-only: it is stable, but isn't the one the Python
+	// translator wrote to conform.json
+	Equals(t, meta.Checksum, "a4563f55c3a5f8ed6fc11e015df83548")
+	NotEquals(t, meta.Checksum, "4e80517459336f80a3ce47d9343f635b")
+
+	idl := barrister.NewIdl(elems)
+	Equals(t, idl.Meta.Checksum, meta.Checksum)
+	Equals(t, idl.Method("A.repeat").Returns.Type, "RepeatResponse")
+}
+
+func TestParseCalc(t *testing.T) {
+	elems, err := ParseFile("../example/calc.idl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Equals(t, len(elems), 3)
+	Equals(t, elems[0].Type, "comment")
+	True(t, strings.HasPrefix(elems[0].Value, "The Calculator service is easy to use."))
+	True(t, strings.HasSuffix(elems[0].Value, "    # y == 34"))
+
+	calc := elems[1]
+	Equals(t, calc.Type, "interface")
+	Equals(t, calc.Name, "Calculator")
+	Equals(t, len(calc.Functions), 2)
+	Equals(t, calc.Functions[0].Comment, "Adds two numbers together and returns the result   ")
+	Equals(t, calc.Functions[1].Params[1].Name, "b")
+	Equals(t, calc.Functions[1].Returns.Type, "float")
+}
+
+func TestChecksumIgnoresCommentsAndOrder(t *testing.T) {
+	a := mustParse(t, `
+struct Person {
+    name string
+    age  int [optional]
+}
+interface Svc {
+    get(id string) Person
+}`)
+	b := mustParse(t, `
+// people
+interface Svc {
+    // gets a person
+    get(id string) Person
+}
+
+struct Person {
+    age  int [optional]
+    // full name
+    name string
+}`)
+	c := mustParse(t, `
+struct Person {
+    name string
+    age  int
+}
+interface Svc {
+    get(id string) Person
+}`)
+
+	Equals(t, a[len(a)-1].Checksum, b[len(b)-1].Checksum)
+	NotEquals(t, a[len(a)-1].Checksum, c[len(c)-1].Checksum)
+}
+
+func TestImportCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "barrister-parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "a.idl"), "import \"b.idl\"\nstruct A {\n  b B\n}\n")
+	writeFile(t, filepath.Join(dir, "b.idl"), "import \"a.idl\"\nstruct B {\n  s string\n}\n")
+
+	_, err = ParseFile(filepath.Join(dir, "a.idl"))
+	if err == nil || !strings.Contains(err.Error(), "import cycle") {
+		t.Errorf("Expected import cycle error, got: %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"struct Foo {\n  name\n}":            "test.idl:3:1: expected type, got '}'",
+		"interface Foo {\n  get(a int\n}":    "test.idl:3:1: expected ',' or ')', got '}'",
+		"enum Foo {\n  a\n":                  "test.idl:3:1: expected enum value or '}', got end of file",
+		"struct Foo {\n  a int [opt]\n}":     "test.idl:2:10: expected 'optional', got 'opt'",
+		"foo Bar {}":                         "test.idl:1:1: expected struct, enum, interface, namespace or import, got 'foo'",
+		"struct Foo {\n  a \"int\n}":         "test.idl:2:5: unterminated string",
+		"namespace a\nnamespace b":           "test.idl:2:1: namespace already declared: a",
+		"import \"missing.idl\"\nstruct A{}": "test.idl:1: unable to import missing.idl",
+		"struct A {\n  b B\n}":               "struct A: field b: unknown type: B",
+	}
+
+	for src, expected := range cases {
+		_, err := Parse("test.idl", []byte(src))
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%q: expected error %q, got: %v", src, expected, err)
+		}
+	}
+}
+
+func mustParse(t *testing.T, src string) []barrister.IdlJsonElem {
+	elems, err := Parse("test.idl", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return elems
+}
+
+func readFile(t *testing.T, fname string) []byte {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func writeFile(t *testing.T, fname string, s string) {
+	err := ioutil.WriteFile(fname, []byte(s), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdlSourceRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "barrister-parser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	expected := []barrister.IdlJsonElem{}
+	err = json.Unmarshal(readFile(t, "../conform/conform.json"), &expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for fname, src := range barrister.NewIdl(expected).IdlSource("conform") {
+		writeFile(t, filepath.Join(dir, fname), string(src))
+	}
+
+	elems, err := ParseFile(filepath.Join(dir, "conform.idl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	DeepEquals(t, withoutMeta(elems), withoutMeta(expected))
+	Equals(t, elems[len(elems)-1].Checksum, barrister.ComputeChecksum(expected))
+}
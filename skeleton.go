@@ -0,0 +1,201 @@
+package barrister
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GenerateSkeleton generates the starting point for a server that
+// implements the IDL's interfaces.  A map is returned whose keys are file
+// names and values are Go source code for package main: one file per
+// interface, with a <Iface>Impl struct whose methods return a -32601
+// JsonRpcError, and a main.go that serves them over HTTP with the generated
+// NewJSONServer.
+//
+// The code generated by GenerateGoWithOptions with the same opts is imported
+// from opts.BaseImport + opts.PkgName.  Unlike generated code, these files
+// are meant to be edited, so callers should never overwrite existing files.
+// An error is returned if an interface's file name would be main.go.
+func (idl *Idl) GenerateSkeleton(opts GoOptions) (map[string][]byte, error) {
+	if opts.Naming == nil {
+		opts.Naming = MixedCapsNaming
+	}
+	if err := idl.checkGoNames(opts.Naming); err != nil {
+		return nil, err
+	}
+
+	// Go names of the elements in the generated package, which must be
+	// qualified with its name in package main
+	names := []string{}
+	for _, elem := range idl.elems {
+		if (elem.Type == "struct" || elem.Type == "enum") && !strings.Contains(elem.Name, ".") {
+			names = append(names, opts.Naming(elem.Name))
+		}
+	}
+
+	files := make(map[string][]byte)
+	ifaceNames := sortedKeys(idl.interfaces)
+	for _, name := range ifaceNames {
+		s := &generateSkeleton{
+			g: &generateGo{
+				idl:              idl,
+				optionalToPtr:    opts.OptionalToPtr,
+				genericOptionals: opts.GenericOptionals,
+				naming:           opts.Naming,
+			},
+			opts:    opts,
+			imports: map[string]bool{},
+		}
+		if len(names) > 0 {
+			s.qualifyRe = regexp.MustCompile(`(^|[^.\w])(` + strings.Join(names, "|") + `)\b`)
+		}
+
+		fname := strings.ToLower(name) + ".go"
+		if fname == "main.go" {
+			return nil, fmt.Errorf("barrister: file for interface %s would overwrite main.go", name)
+		}
+		if _, ok := files[fname]; ok {
+			return nil, fmt.Errorf("barrister: more than one interface is generated as %s", fname)
+		}
+		code, err := format.Source(s.generateImpl(name))
+		if err != nil {
+			return nil, fmt.Errorf("barrister: generated code for %s is invalid: %s", fname, err)
+		}
+		files[fname] = code
+	}
+
+	code, err := format.Source(generateSkeletonMain(opts, ifaceNames))
+	if err != nil {
+		return nil, fmt.Errorf("barrister: generated code for main.go is invalid: %s", err)
+	}
+	files["main.go"] = code
+	return files, nil
+}
+
+type generateSkeleton struct {
+	g    *generateGo
+	opts GoOptions
+
+	// matches the names of elements in the generated package
+	qualifyRe *regexp.Regexp
+
+	// import paths used by the file
+	imports map[string]bool
+}
+
+// qualify adds the generated package name to the Go names in s that refer
+// to elements in the generated package
+func (s *generateSkeleton) qualify(code string) string {
+	if s.qualifyRe == nil || !s.qualifyRe.MatchString(code) {
+		return code
+	}
+	s.imports[s.opts.BaseImport+s.opts.PkgName] = true
+	return s.qualifyRe.ReplaceAllString(code, "${1}"+s.opts.PkgName+".${2}")
+}
+
+// goType returns the Go type of f in package main
+func (s *generateSkeleton) goType(key string, f Field) string {
+	if ns, _ := splitNs(f.Type); ns != "" {
+		s.imports[s.opts.BaseImport+ns] = true
+	}
+	return s.qualify(s.g.goType(key, f))
+}
+
+func (s *generateSkeleton) generateImpl(ifaceName string) []byte {
+	funcs := s.g.idl.interfaces[ifaceName]
+	goIfaceName := s.opts.Naming(ifaceName)
+	goName := goIfaceName + "Impl"
+
+	b := &bytes.Buffer{}
+	line(b, 0, fmt.Sprintf("// %s implements %s.%s", goName, s.opts.PkgName, goIfaceName))
+	if iface, _ := s.g.idl.Interface(ifaceName); strings.TrimSpace(iface.Comment) != "" {
+		line(b, 0, "//")
+		comment(b, 0, iface.Comment)
+	}
+	line(b, 0, fmt.Sprintf("type %s struct{}\n", goName))
+
+	recv := receiverName(goName, funcs)
+
+	for _, fn := range funcs {
+		method := ifaceName + "." + fn.Name
+		params := []string{}
+		for _, p := range fn.Params {
+			params = append(params, fmt.Sprintf("%s %s", escReserved(p.Name), s.goType(method+"."+p.Name, p)))
+		}
+		retType := s.goType(method, fn.Returns)
+		zeroVal := s.qualify(fmt.Sprintf("%v", s.g.zeroVal(method, fn.Returns)))
+
+		comment(b, 0, fn.Comment)
+		line(b, 0, fmt.Sprintf("func (%s %s) %s(%s) (%s, error) {", recv, goName, s.opts.Naming(fn.Name), strings.Join(params, ", "), retType))
+		line(b, 1, fmt.Sprintf("return %s, &barrister.JsonRpcError{Code: -32601, Message: \"%s is not implemented\"}", zeroVal, method))
+		line(b, 0, "}\n")
+	}
+
+	s.imports["github.com/coopernurse/barrister-go"] = true
+	for _, imp := range s.g.typeImports {
+		s.imports[imp] = true
+	}
+	imports := []string{}
+	for imp := range s.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	h := &bytes.Buffer{}
+	line(h, 0, "package main\n")
+	line(h, 0, "import (")
+	for _, imp := range imports {
+		line(h, 1, fmt.Sprintf("%q", imp))
+	}
+	line(h, 0, ")\n")
+	h.Write(b.Bytes())
+	return h.Bytes()
+}
+
+// receiverName returns a receiver name for goName that isn't used as a
+// param name by any of funcs
+func receiverName(goName string, funcs []Function) string {
+	used := map[string]bool{}
+	for _, fn := range funcs {
+		for _, p := range fn.Params {
+			used[escReserved(p.Name)] = true
+		}
+	}
+	for _, name := range []string{strings.ToLower(goName[:1]), "impl"} {
+		if !used[name] {
+			return name
+		}
+	}
+	return "_impl"
+}
+
+func generateSkeletonMain(opts GoOptions, ifaceNames []string) []byte {
+	impls := []string{}
+	for _, name := range ifaceNames {
+		impls = append(impls, opts.Naming(name)+"Impl{}")
+	}
+
+	b := &bytes.Buffer{}
+	line(b, 0, "package main\n")
+	line(b, 0, "import (")
+	line(b, 1, `"flag"`)
+	line(b, 1, `"log"`)
+	line(b, 1, `"net/http"`)
+	line(b, 0, "")
+	line(b, 1, fmt.Sprintf("%q", opts.BaseImport+opts.PkgName))
+	line(b, 0, ")\n")
+	line(b, 0, "func main() {")
+	line(b, 1, `addr := flag.String("addr", ":9233", "Address to listen on")`)
+	line(b, 1, "flag.Parse()\n")
+	line(b, 1, fmt.Sprintf("svr := %s.NewJSONServer(%s.BarristerIdl(), true, %s)", opts.PkgName, opts.PkgName, strings.Join(impls, ", ")))
+	line(b, 1, `http.Handle("/", &svr)`)
+	line(b, 0, "")
+	line(b, 1, `log.Println("Listening on", *addr)`)
+	line(b, 1, "log.Fatal(http.ListenAndServe(*addr, nil))")
+	line(b, 0, "}")
+	return b.Bytes()
+}
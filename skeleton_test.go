@@ -0,0 +1,81 @@
+package barrister
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestGenerateSkeleton(t *testing.T) {
+	idl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := idl.GenerateSkeleton(GoOptions{PkgName: "usersvc", BaseImport: "example.com/gen/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, len(files), 2)
+
+	expected := map[string][]string{
+		"userservice.go": []string{
+			"import (\n\t\"example.com/gen/common\"\n\t\"example.com/gen/usersvc\"\n\t\"github.com/coopernurse/barrister-go\"\n)",
+			"// UserServiceImpl implements usersvc.UserService\ntype UserServiceImpl struct{}",
+			"// returns null if not found\nfunc (u UserServiceImpl) Get(id string) (*usersvc.User, error) {\n" +
+				"\treturn nil, &barrister.JsonRpcError{Code: -32601, Message: \"UserService.get is not implemented\"}",
+			"func (u UserServiceImpl) Find(ids []string, status common.Status) ([]usersvc.User, error) {\n" +
+				"\treturn []usersvc.User{}, &barrister.JsonRpcError{Code: -32601",
+		},
+		"main.go": []string{
+			"\t\"example.com/gen/usersvc\"\n)",
+			"svr := usersvc.NewJSONServer(usersvc.BarristerIdl(), true, UserServiceImpl{})",
+			"http.ListenAndServe(*addr, nil)",
+		},
+	}
+	for fname, lines := range expected {
+		code := string(files[fname])
+		for _, s := range lines {
+			if !strings.Contains(code, s) {
+				t.Errorf("%s does not contain: %s\n%s", fname, s, code)
+			}
+		}
+	}
+}
+
+func TestGenerateSkeletonReceiverName(t *testing.T) {
+	idl := NewBuilder().
+		Interface("Calc").Function("add", "int", "c int", "impl int").
+		MustBuild()
+
+	files, err := idl.GenerateSkeleton(GoOptions{PkgName: "calc", BaseImport: "example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(files["calc.go"])
+	if !strings.Contains(code, "func (_impl CalcImpl) Add(c int64, impl int64) (int64, error) {") {
+		t.Errorf("Unexpected receiver name:\n%s", code)
+	}
+	if strings.Contains(code, "example.com/calc") {
+		t.Errorf("Unused import of generated package:\n%s", code)
+	}
+}
+
+func TestGenerateSkeletonFileCollisions(t *testing.T) {
+	idl := NewBuilder().
+		Interface("Main").Function("run", "bool").
+		MustBuild()
+	_, err := idl.GenerateSkeleton(GoOptions{PkgName: "app", BaseImport: "example.com/"})
+	NotEquals(t, err, nil)
+	Equals(t, err.Error(), "barrister: file for interface Main would overwrite main.go")
+
+	idl = NewBuilder().
+		Interface("Users").Function("get", "bool").
+		Interface("users").Function("get", "bool").
+		MustBuild()
+	identity := func(idlName string) string { return idlName }
+	_, err = idl.GenerateSkeleton(GoOptions{PkgName: "app", BaseImport: "example.com/", Naming: identity})
+	NotEquals(t, err, nil)
+	Equals(t, err.Error(), "barrister: more than one interface is generated as users.go")
+}
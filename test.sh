@@ -4,6 +4,8 @@ set -e
 
 go clean
 go test -v
-go run idl2go/idl2go.go -n -b "github.com/coopernurse/barrister-go/conform/generated/" -d conform/generated conform/conform.json
+go run ./idl2go -n -names mixedcaps -b "github.com/coopernurse/barrister-go/conform/generated/" -d conform/generated conform/conform.json
 go build conform/client.go
 go build conform/server.go
+cp testdata/proxy_bench_test.go conform/generated/conform/
+go test -run NONE -bench . ./conform/generated/conform
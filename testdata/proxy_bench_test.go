@@ -0,0 +1,67 @@
+package conform
+
+// This file is copied into the package generated from conform/conform.json
+// by test.sh and TestGeneratedCodeVets, so that the generated proxy can be
+// benchmarked against the Convert based decoding it replaced:
+//
+//	go test -run NONE -bench . ./conform/generated/conform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coopernurse/barrister-go"
+)
+
+// stubClient returns an A.repeat result, as decoded by the JsonSerializer,
+// without making a call
+type stubClient struct{}
+
+func (stubClient) Call(method string, params ...interface{}) (interface{}, error) {
+	return map[string]interface{}{
+		"status": "ok",
+		"count":  float64(3),
+		"items":  []interface{}{"a", "a", "a"},
+	}, nil
+}
+
+func (stubClient) CallBatch(batch []barrister.JsonRpcRequest) []barrister.JsonRpcResponse {
+	return nil
+}
+
+// BenchmarkRepeatProxy calls the generated AProxy.Repeat, which decodes the
+// result with UnmarshalIdl
+func BenchmarkRepeatProxy(b *testing.B) {
+	p := NewAProxy(stubClient{})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Repeat(RepeatRequest{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRepeatConvert calls A.repeat and converts the result with
+// barrister.Convert, as proxies generated before UnmarshalIdl did
+func BenchmarkRepeatConvert(b *testing.B) {
+	c := stubClient{}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		res, err := c.Call("A.repeat", RepeatRequest{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		retType := BarristerIdl().Method("A.repeat").Returns
+		conv, err := barrister.Convert(BarristerIdl(), &retType, reflect.TypeOf(RepeatResponse{}), res, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, ok := conv.(RepeatResponse); !ok {
+			b.Fatal(conv)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package barrister
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TypeMapping associates a Go type with an IDL scalar type.  Values of GoType
+// travel over the wire as IdlType (e.g. a time.Time sent as an IDL "string"),
+// and are translated using Decode and Encode.
+type TypeMapping struct {
+	// IDL type the Go type is serialized as: "string", "int", "float" or "bool"
+	IdlType string
+
+	// Go type used by handlers and generated code.  Required for runtime
+	// conversion, optional if the mapping is only used by GenerateGo
+	GoType reflect.Type
+
+	// Qualified Go type name and import path used by GenerateGo.
+	// For example: "time.Time" and "time", or "UserId" and "" for a type
+	// declared in the generated package.
+	GoName   string
+	GoImport string
+
+	// Decode converts a value decoded from the wire (string, float64, int64 or bool)
+	// to a value of GoType.  If nil, the value is converted based on the Kind
+	// of GoType (useful for named strings and ints).
+	Decode func(in interface{}) (interface{}, error)
+
+	// Encode converts a value of GoType to a value that serializes as IdlType.
+	// If nil, the value is passed to the Serializer as-is.
+	Encode func(in interface{}) (interface{}, error)
+}
+
+// TimeMapping returns a TypeMapping that sends time.Time values as IDL strings
+// formatted with the given layout (e.g. time.RFC3339)
+func TimeMapping(layout string) TypeMapping {
+	return TypeMapping{
+		IdlType:  "string",
+		GoType:   reflect.TypeOf(time.Time{}),
+		GoName:   "time.Time",
+		GoImport: "time",
+		Decode: func(in interface{}) (interface{}, error) {
+			s, ok := in.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string, got: %T", in)
+			}
+			return time.Parse(layout, s)
+		},
+		Encode: func(in interface{}) (interface{}, error) {
+			return in.(time.Time).Format(layout), nil
+		},
+	}
+}
+
+// DefaultTypes is the TypeRegistry used by any Idl whose Types field is nil
+var DefaultTypes = NewTypeRegistry()
+
+// RegisterType adds m to DefaultTypes
+func RegisterType(m TypeMapping) error {
+	return DefaultTypes.Register(m)
+}
+
+// NewTypeRegistry returns an empty TypeRegistry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		goTypes: map[reflect.Type]*TypeMapping{},
+		idlKeys: map[string]*TypeMapping{},
+		needs:   map[reflect.Type]bool{},
+	}
+}
+
+// TypeRegistry holds custom TypeMappings.
+//
+// Mappings registered by GoType are used by Convert, Server.AddHandler validation
+// and when encoding Server responses.  Mappings registered by IDL key are used
+// by GenerateGo to select the Go type of a field.
+type TypeRegistry struct {
+	mu       sync.RWMutex
+	goTypes  map[reflect.Type]*TypeMapping
+	idlKeys  map[string]*TypeMapping
+	encoders bool
+
+	// cache of types that contain a mapped type with an Encode func
+	needs map[reflect.Type]bool
+}
+
+// Register adds m to the registry, keyed by m.GoType
+func (r *TypeRegistry) Register(m TypeMapping) error {
+	if m.GoType == nil {
+		return fmt.Errorf("barrister: TypeMapping for IDL type %s has no GoType", m.IdlType)
+	}
+	return r.add("", m)
+}
+
+// MapIdl adds m to the registry for use by GenerateGo.  key is either an IDL
+// type name (e.g. "string"), a struct field ("Person.createdAt"), a function
+// param ("UserService.get.userId") or a function return value ("UserService.get").
+//
+// m.IdlType may be empty if the mapping is only used for code generation.
+// If m.GoType is set the mapping is also registered for runtime conversion.
+func (r *TypeRegistry) MapIdl(key string, m TypeMapping) error {
+	if key == "" {
+		return fmt.Errorf("barrister: TypeMapping for %s has an empty IDL key", m.GoName)
+	}
+	if m.GoName == "" {
+		return fmt.Errorf("barrister: TypeMapping for IDL key %s has no GoName", key)
+	}
+	return r.add(key, m)
+}
+
+func (r *TypeRegistry) add(key string, m TypeMapping) error {
+	if (m.IdlType != "" || m.GoType != nil) && !isBuiltinType(m.IdlType) {
+		return fmt.Errorf("barrister: TypeMapping has invalid IDL type: '%s'", m.IdlType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	mp := &m
+	if key != "" {
+		r.idlKeys[key] = mp
+	}
+	if m.GoType != nil {
+		r.goTypes[m.GoType] = mp
+		if m.Encode != nil {
+			r.encoders = true
+		}
+		r.needs = map[reflect.Type]bool{}
+	}
+	return nil
+}
+
+// forGoType returns the mapping registered for t, or nil
+func (r *TypeRegistry) forGoType(t reflect.Type) *TypeMapping {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.goTypes[t]
+}
+
+// forIdl returns the mapping registered for the first key found, or nil
+func (r *TypeRegistry) forIdl(keys ...string) *TypeMapping {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, k := range keys {
+		m, ok := r.idlKeys[k]
+		if ok {
+			return m
+		}
+	}
+	return nil
+}
+
+func (r *TypeRegistry) hasIdlKeys() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.idlKeys) > 0
+}
+
+func (r *TypeRegistry) hasEncoders() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.encoders
+}
+
+// needsEncode returns true if t is, or contains, a mapped type with an Encode func
+func (r *TypeRegistry) needsEncode(t reflect.Type) bool {
+	r.mu.RLock()
+	needs, ok := r.needs[t]
+	r.mu.RUnlock()
+	if ok {
+		return needs
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.needsEncodeLocked(t, map[reflect.Type]bool{})
+}
+
+// needsEncodeLocked walks t.  visiting holds the types being walked by the
+// callers, which are treated as not needing encoding to guard against
+// recursive types.  A false result for a type reached from a recursive
+// type may be wrong until the outermost walk is done, so only true results
+// and the outermost result are cached.
+func (r *TypeRegistry) needsEncodeLocked(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	needs, ok := r.needs[t]
+	if ok {
+		return needs
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	m, ok := r.goTypes[t]
+	if ok && m.Encode != nil {
+		needs = true
+	} else {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			needs = r.needsEncodeLocked(t.Elem(), visiting)
+		case reflect.Struct:
+			for i := 0; i < t.NumField() && !needs; i++ {
+				needs = r.needsEncodeLocked(t.Field(i).Type, visiting)
+			}
+		}
+	}
+
+	delete(visiting, t)
+	if needs || len(visiting) == 0 {
+		r.needs[t] = needs
+	}
+	return needs
+}
+
+// encode returns v with all mapped values replaced by their encoded form.
+// Structs containing mapped values are returned as maps keyed by their JSON names.
+func (r *TypeRegistry) encode(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	t := v.Type()
+	if !r.needsEncode(t) {
+		return v.Interface(), nil
+	}
+
+	m := r.forGoType(t)
+	if m != nil && m.Encode != nil {
+		return m.Encode(v.Interface())
+	}
+
+	if isOptionalType(t) {
+		if !v.Field(1).Bool() {
+			return nil, nil
+		}
+		return r.encode(v.Field(0))
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return r.encode(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		arr := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			el, err := r.encode(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = el
+		}
+		return arr, nil
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		err := r.encodeStruct(v, out)
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	return v.Interface(), nil
+}
+
+func (r *TypeRegistry) encodeStruct(v reflect.Value, out map[string]interface{}) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, omitEmpty, skip := jsonFieldName(sf)
+		if skip {
+			continue
+		}
+
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				err := r.encodeStruct(fv, out)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if sf.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		// absent Optionals are omitted, and null ones are encoded as nil
+		if isOptionalType(fv.Type()) && !fv.Field(1).Bool() && !fv.Field(2).Bool() {
+			continue
+		}
+
+		enc, err := r.encode(fv)
+		if err != nil {
+			return err
+		}
+		out[name] = enc
+	}
+	return nil
+}
+
+// jsonFieldName parses the `json` struct tag on f and returns the name in the tag
+// (empty if none), whether omitempty is set, and whether the field is skipped
+func jsonFieldName(f reflect.StructField) (string, bool, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+// isEmptyValue is taken from the encoding/json standard library
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isBuiltinType returns true if t is one of the IDL scalar types
+func isBuiltinType(t string) bool {
+	switch t {
+	case "string", "int", "float", "bool":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package barrister
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+type UserId int64
+
+type Event struct {
+	Name string    `json:"name"`
+	At   time.Time `json:"at"`
+}
+
+type EventsImpl struct{}
+
+func (e EventsImpl) Add(ev Event, owner UserId) (time.Time, error) {
+	return ev.At.Add(time.Hour), nil
+}
+
+type EventsImpl_BadMapping struct{}
+
+func (e EventsImpl_BadMapping) Add(ev Event, owner time.Time) (time.Time, error) {
+	return owner, nil
+}
+
+func createTypeMapIdl() *Idl {
+	idl := NewIdl([]IdlJsonElem{
+		IdlJsonElem{Type: "struct", Name: "Event", Fields: []Field{
+			Field{Name: "name", Type: "string"},
+			Field{Name: "at", Type: "string"},
+		}},
+		IdlJsonElem{Type: "interface", Name: "Events", Functions: []Function{
+			Function{Name: "add",
+				Params: []Field{
+					Field{Name: "ev", Type: "Event"},
+					Field{Name: "owner", Type: "int"},
+				},
+				Returns: Field{Type: "string"}},
+		}},
+	})
+	idl.Types = NewTypeRegistry()
+	err := idl.Types.Register(TimeMapping(time.RFC3339))
+	if err != nil {
+		panic(err)
+	}
+	return idl
+}
+
+func TestConvertTypeMapping(t *testing.T) {
+	idl := createTypeMapIdl()
+	field := &Field{Type: "Event"}
+	input := map[string]interface{}{"name": "launch", "at": "2014-03-01T10:00:00Z"}
+
+	val, err := Convert(idl, field, reflect.TypeOf(Event{}), input, "")
+	Equals(t, err, nil)
+
+	expected := Event{Name: "launch", At: time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)}
+	DeepEquals(t, val, expected)
+
+	input["at"] = "not a time"
+	_, err = Convert(idl, field, reflect.TypeOf(Event{}), input, "")
+	NotEquals(t, err, nil)
+
+	// time.Time is mapped to IDL strings, so an int field may not use it
+	_, err = Convert(idl, &Field{Type: "int"}, reflect.TypeOf(time.Time{}), 10.0, "")
+	NotEquals(t, err, nil)
+}
+
+func TestAddHandlerTypeMapping(t *testing.T) {
+	idl := createTypeMapIdl()
+	svr := NewJSONServer(idl, false)
+	svr.AddHandler("Events", EventsImpl{})
+
+	fx := func() {
+		defer func() {
+			recover()
+		}()
+		svr.AddHandler("Events", EventsImpl_BadMapping{})
+		t.Errorf("AddHandler allowed time.Time for an IDL int param")
+	}
+	fx()
+}
+
+func TestServerEncodesMappedTypes(t *testing.T) {
+	idl := createTypeMapIdl()
+	svr := NewJSONServer(idl, false)
+	svr.AddHandler("Events", EventsImpl{})
+
+	req := `{"jsonrpc":"2.0","id":"1","method":"Events.add","params":[{"name":"a","at":"2014-03-01T10:00:00Z"},10]}`
+	resp := JsonRpcResponse{}
+	err := json.Unmarshal(svr.InvokeBytes(newHeaders(), []byte(req)), &resp)
+	Equals(t, err, nil)
+	if resp.Error != nil {
+		t.Fatalf("Events.add returned err: %v", resp.Error)
+	}
+	Equals(t, resp.Result, "2014-03-01T11:00:00Z")
+}
+
+func TestEncodeValueStruct(t *testing.T) {
+	idl := createTypeMapIdl()
+	ev := Event{Name: "a", At: time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)}
+
+	enc, err := idl.EncodeValue([]*Event{&ev, nil})
+	Equals(t, err, nil)
+
+	expected := []interface{}{
+		map[string]interface{}{"name": "a", "at": "2014-03-01T10:00:00Z"},
+		nil,
+	}
+	DeepEquals(t, enc, expected)
+}
+
+type recA struct {
+	B *recB
+	T time.Time
+}
+
+type recB struct {
+	A *recA
+}
+
+func TestEncodeValueMutuallyRecursive(t *testing.T) {
+	idl := createTypeMapIdl()
+	at := time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// walking recA first must not cache recB as not needing encoding
+	enc, err := idl.EncodeValue(recA{T: at})
+	Equals(t, err, nil)
+	DeepEquals(t, enc, map[string]interface{}{"B": nil, "T": "2014-03-01T10:00:00Z"})
+
+	enc, err = idl.EncodeValue(recB{A: &recA{T: at}})
+	Equals(t, err, nil)
+	DeepEquals(t, enc, map[string]interface{}{
+		"A": map[string]interface{}{"B": nil, "T": "2014-03-01T10:00:00Z"},
+	})
+}
+
+func TestGenerateGoTypeMapping(t *testing.T) {
+	idl := createTypeMapIdl()
+	idl.Types.MapIdl("Event.at", TypeMapping{GoName: "time.Time", GoImport: "time"})
+	idl.Types.MapIdl("Events.add.owner", TypeMapping{GoName: "UserId"})
+	idl.Types.MapIdl("Events.add", TypeMapping{GoName: "time.Time", GoImport: "time"})
+
+	code := string(idl.GenerateGo("events", "", false)["events"])
+
+	expected := []string{
+		"\"time\"",
+		"At   time.Time `json:\"at\"`",
+		"Add(ev Event, owner UserId) (time.Time, error)",
+		"_enc_ev, _err := BarristerIdl().EncodeValue(ev)",
+		"return *new(time.Time), _err",
+	}
+	for _, s := range expected {
+		if !strings.Contains(code, s) {
+			t.Errorf("Generated code does not contain: %s\n%s", s, code)
+		}
+	}
+}
+
+func TestGenerateGoDispatcherTypeMapping(t *testing.T) {
+	idl := createTypeMapIdl()
+	idl.Types.MapIdl("Event.at", TypeMapping{GoName: "time.Time", GoImport: "time"})
+
+	pkgNameToCode, err := idl.GenerateGoWithOptions(GoOptions{PkgName: "events", Dispatchers: true})
+	Equals(t, err, nil)
+	code := string(pkgNameToCode["events"])
+
+	// params that use mapped types are converted with Convert
+	expected := "if _x, _err := barrister.Convert(idl, &idl.Method(\"Events.add\").Params[0], reflect.TypeOf(_p0), params[0], \"\"); _err != nil {"
+	if !strings.Contains(code, expected) {
+		t.Errorf("Generated code does not contain: %s\n%s", expected, code)
+	}
+	if strings.Contains(code, "func (s *Event) UnmarshalIdl") {
+		t.Errorf("Generated code has UnmarshalIdl for a struct with mapped types\n%s", code)
+	}
+}
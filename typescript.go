@@ -0,0 +1,390 @@
+package barrister
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tsReserved are the TypeScript reserved words that can't be used as param
+// names
+var tsReserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true,
+	"continue": true, "debugger": true, "default": true, "delete": true,
+	"do": true, "else": true, "enum": true, "export": true, "extends": true,
+	"false": true, "finally": true, "for": true, "function": true, "if": true,
+	"import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true,
+	"true": true, "try": true, "typeof": true, "var": true, "void": true,
+	"while": true, "with": true, "implements": true, "interface": true,
+	"let": true, "package": true, "private": true, "protected": true,
+	"public": true, "static": true, "yield": true, "await": true,
+}
+
+var tsIdentRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// GenerateTypeScript generates a TypeScript module for the IDL.  It
+// contains:
+//
+// * an interface for each struct, which extends the interface of its parent
+//
+// * a union of string literals for each enum, and a <Enum>Values array of
+// its values
+//
+// * a <Iface>Client class for each interface, whose methods return a
+// Promise of the typed result, and a <Iface>Batch class that queues the
+// same calls on a Batch
+//
+// * the JSON-RPC runtime used by the clients: Client, Batch, RpcError and
+// fetchTransport, which POSTs requests to a URL with fetch
+//
+// Optional fields are optional properties that also accept null.  Since
+// TypeScript names can't contain dots, the names of types in namespaces
+// have the dot replaced with an underscore, e.g. common_User.  An error is
+// returned if two IDL names are generated as the same TypeScript name, or
+// if one is generated as a name used by the runtime.
+func (idl *Idl) GenerateTypeScript() ([]byte, error) {
+	if err := idl.checkTSNames(); err != nil {
+		return nil, err
+	}
+
+	b := &bytes.Buffer{}
+	line(b, 0, fmt.Sprintf("// Code generated by idl2go from IDL generated by Barrister v%s. DO NOT EDIT.\n", idl.Meta.BarristerVersion))
+	line(b, 0, fmt.Sprintf("export const BarristerVersion = %s;", strconv.Quote(idl.Meta.BarristerVersion)))
+	line(b, 0, fmt.Sprintf("export const BarristerChecksum = %s;", strconv.Quote(idl.Meta.Checksum)))
+	line(b, 0, fmt.Sprintf("export const BarristerDateGenerated = %d;\n", idl.Meta.DateGenerated/int64(time.Millisecond)))
+
+	for _, e := range idl.Enums() {
+		name := tsName(e.Name)
+		vals := make([]string, len(e.Values))
+		for i, v := range e.Values {
+			vals[i] = strconv.Quote(v.Value)
+		}
+		union := strings.Join(vals, " | ")
+		if len(vals) == 0 {
+			union = "never"
+		}
+
+		tsComment(b, 0, e.Comment)
+		line(b, 0, fmt.Sprintf("export type %s = %s;\n", name, union))
+		line(b, 0, fmt.Sprintf("export const %sValues: readonly %s[] = [%s];\n", name, name, strings.Join(vals, ", ")))
+	}
+
+	for _, s := range idl.Structs() {
+		extends := ""
+		if s.Extends != "" {
+			extends = " extends " + tsName(s.Extends)
+		}
+		tsComment(b, 0, s.Comment)
+		line(b, 0, fmt.Sprintf("export interface %s%s {", tsName(s.Name), extends))
+		for _, f := range s.Fields {
+			name := f.Name
+			if !tsIdentRe.MatchString(name) {
+				name = strconv.Quote(name)
+			}
+			if f.Optional {
+				name += "?"
+			}
+			tsComment(b, 1, f.Comment)
+			line(b, 1, fmt.Sprintf("%s: %s;", name, tsType(f)))
+		}
+		line(b, 0, "}\n")
+	}
+
+	b.WriteString(tsRuntime)
+
+	for _, iface := range idl.Interfaces() {
+		generateTSClient(b, iface, "Client", "client: Client", "this.client.call")
+		generateTSClient(b, iface, "Batch", "batch: Batch", "this.batch.add")
+	}
+	return b.Bytes(), nil
+}
+
+// tsRuntimeNames are the names declared by the generated constants and
+// tsRuntime, and the globals that tsRuntime uses, which IDL names must not
+// shadow
+var tsRuntimeNames = []string{
+	"BarristerVersion", "BarristerChecksum", "BarristerDateGenerated",
+	"JsonRpcRequest", "JsonRpcErrorObject", "JsonRpcResponse", "RpcError",
+	"Transport", "fetchTransport", "toError", "Client", "BatchCall", "Batch",
+	"Array", "Error", "Headers", "JSON", "Map", "Promise", "RequestInit",
+	"String", "fetch",
+}
+
+// checkTSNames returns an error if two IDL names are generated as the same
+// TypeScript name, or if one is generated as one of tsRuntimeNames
+func (idl *Idl) checkTSNames() error {
+	seen := map[string]string{}
+	for _, name := range tsRuntimeNames {
+		seen[name] = ""
+	}
+	check := func(ts string, idlName string) error {
+		other, ok := seen[ts]
+		if ok && other == "" {
+			return fmt.Errorf("barrister: IDL name %q is generated as TypeScript name %s, which is used by the runtime", idlName, ts)
+		} else if ok {
+			return fmt.Errorf("barrister: IDL names %q and %q are both generated as TypeScript name %s", other, idlName, ts)
+		}
+		seen[ts] = idlName
+		return nil
+	}
+
+	for _, e := range idl.Enums() {
+		if err := check(tsName(e.Name), e.Name); err != nil {
+			return err
+		}
+		if err := check(tsName(e.Name)+"Values", e.Name); err != nil {
+			return err
+		}
+	}
+	for _, s := range idl.Structs() {
+		if err := check(tsName(s.Name), s.Name); err != nil {
+			return err
+		}
+	}
+	for _, iface := range idl.Interfaces() {
+		if err := check(tsName(iface.Name)+"Client", iface.Name); err != nil {
+			return err
+		}
+		if err := check(tsName(iface.Name)+"Batch", iface.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateTSClient writes a class for iface whose methods pass their params
+// to call
+func generateTSClient(b *bytes.Buffer, iface Interface, suffix string, field string, call string) {
+	tsComment(b, 0, iface.Comment)
+	line(b, 0, fmt.Sprintf("export class %s%s {", tsName(iface.Name), suffix))
+	line(b, 1, fmt.Sprintf("constructor(readonly %s) {}", field))
+	for _, fn := range iface.Functions {
+		params := []string{}
+		args := []string{}
+		for _, p := range fn.Params {
+			name := p.Name
+			if tsReserved[name] {
+				name += "_"
+			}
+			params = append(params, fmt.Sprintf("%s: %s", name, tsType(p)))
+			args = append(args, name)
+		}
+		ret := tsType(fn.Returns)
+
+		line(b, 0, "")
+		tsComment(b, 1, fn.Comment)
+		line(b, 1, fmt.Sprintf("%s(%s): Promise<%s> {", fn.Name, strings.Join(params, ", "), ret))
+		line(b, 2, fmt.Sprintf("return %s<%s>(%s, [%s]);", call, ret, strconv.Quote(iface.Name+"."+fn.Name), strings.Join(args, ", ")))
+		line(b, 1, "}")
+	}
+	line(b, 0, "}\n")
+}
+
+// tsName returns the TypeScript name of an IDL struct, enum or interface
+func tsName(idlName string) string {
+	return strings.Replace(idlName, ".", "_", -1)
+}
+
+// tsType returns the TypeScript type of a field, param or function result
+func tsType(f Field) string {
+	var t string
+	switch f.Type {
+	case "string":
+		t = "string"
+	case "int", "float":
+		t = "number"
+	case "bool":
+		t = "boolean"
+	default:
+		t = tsName(f.Type)
+	}
+	if f.IsArray {
+		t += "[]"
+	}
+	if f.Optional {
+		t += " | null"
+	}
+	return t
+}
+
+// tsComment writes an IDL comment as a JSDoc comment
+func tsComment(b *bytes.Buffer, level int, comment string) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return
+	}
+	line(b, level, "/**")
+	for _, ln := range strings.Split(comment, "\n") {
+		ln = strings.TrimRight(strings.Replace(ln, "*/", "*\\/", -1), " \t\r")
+		if ln == "" {
+			line(b, level, " *")
+		} else {
+			line(b, level, " * "+ln)
+		}
+	}
+	line(b, level, " */")
+}
+
+// tsRuntime is the JSON-RPC client used by the generated classes
+const tsRuntime = `export interface JsonRpcRequest {
+	jsonrpc: "2.0";
+	id: string;
+	method: string;
+	params: unknown[];
+}
+
+export interface JsonRpcErrorObject {
+	code: number;
+	message: string;
+	data?: unknown;
+}
+
+export interface JsonRpcResponse {
+	jsonrpc: "2.0";
+	id?: string | null;
+	result?: unknown;
+	error?: JsonRpcErrorObject;
+}
+
+/** RpcError is thrown when a call returns a JSON-RPC error */
+export class RpcError extends Error {
+	constructor(readonly code: number, message: string, readonly data?: unknown) {
+		super(message);
+		this.name = "RpcError";
+	}
+}
+
+/**
+ * Transport sends a request, or a batch of requests, and returns the
+ * decoded response
+ */
+export type Transport = (req: JsonRpcRequest | JsonRpcRequest[]) => Promise<JsonRpcResponse | JsonRpcResponse[]>;
+
+/**
+ * fetchTransport returns a Transport that POSTs requests to url.  init is
+ * merged into each request, e.g. to add headers or credentials.
+ */
+export function fetchTransport(url: string, init: RequestInit = {}): Transport {
+	return async (req) => {
+		const headers = new Headers(init.headers);
+		headers.set("Content-Type", "application/json");
+		const resp = await fetch(url, { ...init, method: "POST", headers, body: JSON.stringify(req) });
+		if (!resp.ok) {
+			throw new RpcError(-32603, "HTTP " + resp.status + " " + resp.statusText);
+		}
+		return resp.json();
+	};
+}
+
+function toError(err: JsonRpcErrorObject): RpcError {
+	return new RpcError(err.code, err.message, err.data);
+}
+
+/** Client makes JSON-RPC calls using a Transport */
+export class Client {
+	private nextId = 1;
+
+	constructor(readonly transport: Transport) {}
+
+	/** call calls method and returns its result */
+	async call<T>(method: string, params: unknown[]): Promise<T> {
+		const req: JsonRpcRequest = { jsonrpc: "2.0", id: String(this.nextId++), method, params };
+		const resp = (await this.transport(req)) as JsonRpcResponse;
+		if (resp.error) {
+			throw toError(resp.error);
+		}
+		return resp.result as T;
+	}
+
+	/** batch creates a Batch that is sent using this Client's Transport */
+	batch(): Batch {
+		return new Batch(this.transport);
+	}
+}
+
+interface BatchCall {
+	req: JsonRpcRequest;
+	resolve: (result: unknown) => void;
+	reject: (err: Error) => void;
+}
+
+/**
+ * Batch queues calls to send in a single JSON-RPC batch request.  The
+ * Promise returned by add settles when the Batch is sent.  A Batch can only
+ * be sent once.
+ */
+export class Batch {
+	private calls: BatchCall[] = [];
+	private sent = false;
+
+	constructor(readonly transport: Transport) {}
+
+	get length(): number {
+		return this.calls.length;
+	}
+
+	/** add queues a call to method */
+	add<T>(method: string, params: unknown[]): Promise<T> {
+		if (this.sent) {
+			return Promise.reject(new Error("barrister: batch has already been sent"));
+		}
+		const req: JsonRpcRequest = { jsonrpc: "2.0", id: String(this.calls.length + 1), method, params };
+		return new Promise<T>((resolve, reject) => {
+			this.calls.push({ req, resolve: resolve as (result: unknown) => void, reject });
+		});
+	}
+
+	/**
+	 * send sends the queued calls and settles their Promises.  It rejects if
+	 * the request as a whole failed, in which case each call is rejected
+	 * with the same error.
+	 */
+	async send(): Promise<void> {
+		if (this.sent) {
+			throw new Error("barrister: batch has already been sent");
+		}
+		this.sent = true;
+		if (this.calls.length === 0) {
+			return;
+		}
+
+		let resps: JsonRpcResponse | JsonRpcResponse[];
+		try {
+			resps = await this.transport(this.calls.map((c) => c.req));
+		} catch (e) {
+			const err = e instanceof Error ? e : new Error(String(e));
+			this.calls.forEach((c) => c.reject(err));
+			throw err;
+		}
+
+		// a single response without an id means the batch was rejected
+		if (!Array.isArray(resps)) {
+			const err = resps.error ? toError(resps.error) : new RpcError(-32603, "barrister: invalid batch response");
+			this.calls.forEach((c) => c.reject(err));
+			throw err;
+		}
+
+		const byId = new Map<string, JsonRpcResponse>();
+		for (const resp of resps) {
+			if (resp.id != null) {
+				byId.set(resp.id, resp);
+			}
+		}
+		for (const c of this.calls) {
+			const resp = byId.get(c.req.id);
+			if (!resp) {
+				c.reject(new RpcError(-32603, "barrister: " + c.req.method + ": no response in batch for request id " + c.req.id));
+			} else if (resp.error) {
+				c.reject(toError(resp.error));
+			} else {
+				c.resolve(resp.result);
+			}
+		}
+	}
+}
+
+`
@@ -0,0 +1,149 @@
+package barrister
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	ts := generateTS(t, parseTestIdl())
+
+	expected := []string{
+		`export const BarristerDateGenerated = 1337654725230;`,
+		`export type MathOp = "add" | "multiply";`,
+		`export const MathOpValues: readonly MathOp[] = ["add", "multiply"];`,
+		"/**\n * testing struct inheritance\n */\nexport interface RepeatResponse extends Response {\n\tcount: number;\n\titems: string[];\n}",
+		"\temail?: string | null;",
+		"export class AClient {\n\tconstructor(readonly client: Client) {}",
+		"\tadd(a: number, b: number): Promise<number> {\n\t\treturn this.client.call<number>(\"A.add\", [a, b]);\n\t}",
+		"export class ABatch {\n\tconstructor(readonly batch: Batch) {}",
+		"\t\treturn this.batch.add<number>(\"A.add\", [a, b]);",
+		"\techo(s: string): Promise<string | null> {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(ts, s) {
+			t.Errorf("generated TypeScript does not contain: %s", s)
+		}
+	}
+}
+
+func TestGenerateTypeScriptNamespaces(t *testing.T) {
+	idl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := generateTS(t, idl)
+
+	expected := []string{
+		`export type common_Status = "ok" | "err";`,
+		"export interface User extends common_Base {",
+		"\t/**\n\t * most recent first\n\t */\n\temails?: string[] | null;",
+		"\tget(id: string): Promise<User | null> {",
+		"\t\treturn this.client.call<User | null>(\"UserService.get\", [id]);",
+		"\tfind(ids: string[], status: common_Status): Promise<User[]> {",
+	}
+	for _, s := range expected {
+		if !strings.Contains(ts, s) {
+			t.Errorf("generated TypeScript does not contain: %s", s)
+		}
+	}
+}
+
+func TestGenerateTypeScriptReservedParam(t *testing.T) {
+	idl := NewBuilder().
+		Interface("Svc").Function("run", "bool", "function string").
+		MustBuild()
+
+	ts := generateTS(t, idl)
+	True(t, strings.Contains(ts, "\trun(function_: string): Promise<boolean> {\n\t\treturn this.client.call<boolean>(\"Svc.run\", [function_]);"))
+}
+
+func TestGenerateTypeScriptNameCollisions(t *testing.T) {
+	tests := []struct {
+		idl *Idl
+		err string
+	}{
+		{
+			NewBuilder().Struct("Client").Field("id", "string").MustBuild(),
+			`barrister: IDL name "Client" is generated as TypeScript name Client, which is used by the runtime`,
+		},
+		{
+			NewBuilder().Struct("Error").Field("code", "int").MustBuild(),
+			`barrister: IDL name "Error" is generated as TypeScript name Error, which is used by the runtime`,
+		},
+		{
+			NewBuilder().Enum("BatchCall").Value("a").MustBuild(),
+			`barrister: IDL name "BatchCall" is generated as TypeScript name BatchCall, which is used by the runtime`,
+		},
+		{
+			NewBuilder().
+				Enum("Color").Value("red").
+				Struct("ColorValues").Field("rgb", "string").
+				MustBuild(),
+			`barrister: IDL names "Color" and "ColorValues" are both generated as TypeScript name ColorValues`,
+		},
+		{
+			NewBuilder().
+				Struct("UserClient").Field("id", "string").
+				Interface("User").Function("get", "bool").
+				MustBuild(),
+			`barrister: IDL names "UserClient" and "User" are both generated as TypeScript name UserClient`,
+		},
+	}
+	for _, test := range tests {
+		_, err := test.idl.GenerateTypeScript()
+		if err == nil {
+			t.Errorf("GenerateTypeScript didn't return: %s", test.err)
+		} else {
+			Equals(t, err.Error(), test.err)
+		}
+	}
+}
+
+// TestGenerateTypeScriptCompiles type checks the generated module with tsc,
+// if it is installed
+func TestGenerateTypeScriptCompiles(t *testing.T) {
+	tsc, err := exec.LookPath("tsc")
+	if err != nil {
+		t.Skip("tsc not found")
+	}
+
+	dir, err := ioutil.TempDir("", "barrister-ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	userIdl, err := buildUserIdl()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*Idl{"conform.ts": parseTestIdl(), "usersvc.ts": userIdl}
+	for fname, idl := range files {
+		path := filepath.Join(dir, fname)
+		err = ioutil.WriteFile(path, []byte(generateTS(t, idl)), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command(tsc, "--noEmit", "--strict", "--target", "es2020", "--lib", "es2020,dom", path)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("tsc failed for %s: %s\n%s", fname, err, out)
+		}
+	}
+}
+
+func generateTS(t *testing.T, idl *Idl) string {
+	ts, err := idl.GenerateTypeScript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(ts)
+}
@@ -0,0 +1,215 @@
+package barrister
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a single semantic problem in an IDL document
+type ValidationError struct {
+	// Element is the kind and name of the element with the problem,
+	// e.g. "struct Person"
+	Element string
+
+	// Location within the element, e.g. "field email" or
+	// "function get param id".  Empty if the problem is with the
+	// element itself.
+	Location string
+
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Location == "" {
+		return fmt.Sprintf("%s: %s", e.Element, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Element, e.Location, e.Message)
+}
+
+// ValidationErrors is the list of problems found by ValidateIdl
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// ValidateIdl checks that the IDL elements form a valid contract.  It
+// reports:
+//
+// * duplicate element names
+//
+// * field, param and return types that are not a built in type, struct or enum
+//
+// * structs that extend a missing struct, or are part of a cyclic extends chain
+//
+// * duplicate fields, including fields that shadow a field of a parent struct
+//
+// * enums with no values, or duplicate values
+//
+// * duplicate functions or params
+//
+// If any problems are found a ValidationErrors is returned.  Duplicate element
+// names are reported first, followed by the problems in each element in order.
+func ValidateIdl(elems []IdlJsonElem) error {
+	v := &validator{
+		kinds:   map[string]string{},
+		structs: map[string]IdlJsonElem{},
+	}
+	v.validate(elems)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	// element name -> element type
+	kinds   map[string]string
+	structs map[string]IdlJsonElem
+	errs    ValidationErrors
+}
+
+func (v *validator) errorf(el IdlJsonElem, location string, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Element:  el.Type + " " + el.Name,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) validate(elems []IdlJsonElem) {
+	for _, el := range elems {
+		if el.Type != "struct" && el.Type != "enum" && el.Type != "interface" {
+			continue
+		}
+		if el.Name == "" {
+			v.errorf(el, "", "missing name")
+			continue
+		}
+		if kind, ok := v.kinds[el.Name]; ok {
+			v.errorf(el, "", "name already declared by %s %s", kind, el.Name)
+			continue
+		}
+		v.kinds[el.Name] = el.Type
+		if el.Type == "struct" {
+			v.structs[el.Name] = el
+		}
+	}
+
+	for _, el := range elems {
+		switch el.Type {
+		case "struct":
+			v.validateStruct(el)
+		case "enum":
+			v.validateEnum(el)
+		case "interface":
+			v.validateInterface(el)
+		}
+	}
+}
+
+func (v *validator) validateStruct(el IdlJsonElem) {
+	seen := map[string]bool{}
+	for _, f := range el.Fields {
+		loc := "field " + f.Name
+		if f.Name == "" {
+			v.errorf(el, "field", "missing name")
+		} else if seen[f.Name] {
+			v.errorf(el, loc, "duplicate field")
+		}
+		seen[f.Name] = true
+		v.validateType(el, loc, f.Type)
+	}
+
+	// walk the extends chain, checking for missing parents, cycles and
+	// fields that shadow inherited fields
+	visited := map[string]bool{el.Name: true}
+	child := el
+	for child.Extends != "" {
+		parent, ok := v.structs[child.Extends]
+		if !ok {
+			if child.Name == el.Name {
+				if kind, exists := v.kinds[child.Extends]; exists {
+					v.errorf(el, "extends", "%s is a %s, not a struct", child.Extends, kind)
+				} else {
+					v.errorf(el, "extends", "unknown struct: %s", child.Extends)
+				}
+			}
+			return
+		}
+		if visited[parent.Name] {
+			v.errorf(el, "extends", "cyclic extends chain through struct %s", parent.Name)
+			return
+		}
+		visited[parent.Name] = true
+
+		for _, f := range parent.Fields {
+			if seen[f.Name] {
+				v.errorf(el, "field "+f.Name, "shadows field inherited from struct %s", parent.Name)
+			}
+		}
+		child = parent
+	}
+}
+
+func (v *validator) validateEnum(el IdlJsonElem) {
+	if len(el.Values) == 0 {
+		v.errorf(el, "", "enum has no values")
+	}
+	seen := map[string]bool{}
+	for _, val := range el.Values {
+		if val.Value == "" {
+			v.errorf(el, "value", "missing value")
+		} else if seen[val.Value] {
+			v.errorf(el, "value "+val.Value, "duplicate value")
+		}
+		seen[val.Value] = true
+	}
+}
+
+func (v *validator) validateInterface(el IdlJsonElem) {
+	seenFuncs := map[string]bool{}
+	for _, fn := range el.Functions {
+		loc := "function " + fn.Name
+		if fn.Name == "" {
+			v.errorf(el, "function", "missing name")
+		} else if seenFuncs[fn.Name] {
+			v.errorf(el, loc, "duplicate function")
+		}
+		seenFuncs[fn.Name] = true
+
+		seenParams := map[string]bool{}
+		for _, p := range fn.Params {
+			ploc := loc + " param " + p.Name
+			if p.Name == "" {
+				v.errorf(el, loc+" param", "missing name")
+			} else if seenParams[p.Name] {
+				v.errorf(el, ploc, "duplicate param")
+			}
+			seenParams[p.Name] = true
+			v.validateType(el, ploc, p.Type)
+		}
+
+		v.validateType(el, loc+" returns", fn.Returns.Type)
+	}
+}
+
+func (v *validator) validateType(el IdlJsonElem, location string, typeName string) {
+	if isBuiltinType(typeName) {
+		return
+	} else if typeName == "" {
+		v.errorf(el, location, "missing type")
+		return
+	}
+
+	kind, ok := v.kinds[typeName]
+	if !ok {
+		v.errorf(el, location, "unknown type: %s", typeName)
+	} else if kind == "interface" {
+		v.errorf(el, location, "%s is an interface and can't be used as a type", typeName)
+	}
+}
@@ -0,0 +1,91 @@
+package barrister
+
+import (
+	"testing"
+
+	. "github.com/couchbaselabs/go.assert"
+)
+
+func validationMessages(t *testing.T, elems []IdlJsonElem) []string {
+	err := ValidateIdl(elems)
+	if err == nil {
+		return []string{}
+	}
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got: %T %v", err, err)
+	}
+	msgs := make([]string, len(verrs))
+	for i, verr := range verrs {
+		msgs[i] = verr.Error()
+	}
+	return msgs
+}
+
+func TestValidateConformIdl(t *testing.T) {
+	idl, err := ParseIdlJsonFile("test/conform.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equals(t, ValidateIdl(idl.elems), nil)
+}
+
+func TestValidateIdl(t *testing.T) {
+	elems := []IdlJsonElem{
+		{Type: "struct", Name: "Base", Fields: []Field{
+			{Name: "id", Type: "string"},
+		}},
+		{Type: "struct", Name: "Person", Extends: "Base", Fields: []Field{
+			{Name: "id", Type: "int"},
+			{Name: "name", Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "address", Type: "Address"},
+		}},
+		{Type: "struct", Name: "Orphan", Extends: "Missing"},
+		{Type: "struct", Name: "Loop1", Extends: "Loop2"},
+		{Type: "struct", Name: "Loop2", Extends: "Loop1"},
+		{Type: "enum", Name: "Empty"},
+		{Type: "enum", Name: "Color", Values: []EnumValue{{Value: "red"}, {Value: "red"}}},
+		{Type: "enum", Name: "Base", Values: []EnumValue{{Value: "a"}}},
+		{Type: "interface", Name: "Svc", Functions: []Function{
+			{Name: "get", Params: []Field{{Name: "id", Type: "Id"}, {Name: "id", Type: "string"}},
+				Returns: Field{Type: "Svc"}},
+			{Name: "get", Returns: Field{Type: "Person", Optional: true}},
+		}},
+	}
+
+	expected := []string{
+		"enum Base: name already declared by struct Base",
+		"struct Person: field name: duplicate field",
+		"struct Person: field address: unknown type: Address",
+		"struct Person: field id: shadows field inherited from struct Base",
+		"struct Orphan: extends: unknown struct: Missing",
+		"struct Loop1: extends: cyclic extends chain through struct Loop1",
+		"struct Loop2: extends: cyclic extends chain through struct Loop2",
+		"enum Empty: enum has no values",
+		"enum Color: value red: duplicate value",
+		"interface Svc: function get param id: unknown type: Id",
+		"interface Svc: function get param id: duplicate param",
+		"interface Svc: function get returns: Svc is an interface and can't be used as a type",
+		"interface Svc: function get: duplicate function",
+	}
+
+	msgs := validationMessages(t, elems)
+	Equals(t, len(msgs), len(expected))
+	for i := 0; i < len(msgs) && i < len(expected); i++ {
+		Equals(t, msgs[i], expected[i])
+	}
+}
+
+func TestParseIdlJsonReturnsValidationErrors(t *testing.T) {
+	_, err := ParseIdlJson([]byte(`[{"type": "struct", "name": "A", "extends": "B", "fields": []}]`))
+	Equals(t, err.Error(), "struct A: extends: unknown struct: B")
+}
+
+func TestNewIdlCyclicExtends(t *testing.T) {
+	idl := NewIdl([]IdlJsonElem{
+		{Type: "struct", Name: "A", Extends: "B", Fields: []Field{{Name: "a", Type: "string"}}},
+		{Type: "struct", Name: "B", Extends: "A", Fields: []Field{{Name: "b", Type: "string"}}},
+	})
+	Equals(t, len(idl.structs["A"].allFields), 2)
+}